@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+	"github.com/yuin/goldmark/ast"
+)
+
+// ---------- 附件 (attachment) sections ----------
+
+// attachmentMarker turns a level-1 heading into the start of an attachment:
+//
+//	# 专项检查自查表 {attachment}
+//
+// Everything after such a heading, up to the next one, is typeset as that
+// attachment on a new page after the signature and before the 版记.
+const attachmentMarker = "{attachment}"
+
+// isAttachmentHeading reports whether n is a level-1 heading carrying the attachment marker.
+func (c *converter) isAttachmentHeading(n ast.Node) bool {
+	h, ok := n.(*ast.Heading)
+	if !ok || h.Level != 1 {
+		return false
+	}
+	_, marker := stripTrailingMarker(strings.TrimSpace(c.plainText(h)))
+	return marker == "attachment"
+}
+
+// countAttachments counts the attachment headings among the top-level blocks of doc.
+func (c *converter) countAttachments(doc ast.Node) int {
+	n := 0
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		if c.isAttachmentHeading(child) {
+			n++
+		}
+	}
+	return n
+}
+
+// attachmentLabel returns the label printed at the top-left of an attachment.
+// A single attachment is labelled just "附件", matching its unnumbered 附件说明.
+func attachmentLabel(index, total int) string {
+	if total <= 1 {
+		return "附件"
+	}
+	return fmt.Sprintf("附件%d", index)
+}
+
+// renderAttachmentHeading starts a new attachment: page break, numbered label,
+// centred title and reset heading counters (see attachment-page in template_head.typ).
+func (c *converter) renderAttachmentHeading(h *ast.Heading) string {
+	c.attachmentCounter++
+	c.resetHeadingNumbers()
+	title, _ := stripTrailingMarker(strings.TrimSpace(c.plainText(h)))
+	c.attachmentTitles = append(c.attachmentTitles, convertPunctuation(title))
+
+	content := strings.TrimRight(c.renderInlines(h), " \n")
+	content = strings.TrimRight(strings.TrimSuffix(content, attachmentMarker), " ")
+
+	label := attachmentLabel(c.attachmentCounter, c.attachmentTotal)
+	page := typst.Call("attachment-page", typst.Pos(typst.Str(label))).Body(typst.Markup(content))
+	return string(typst.Embed(page)) + "\n\n"
+}
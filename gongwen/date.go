@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+)
+
+// ---------- Dates ----------
+
+// Date styles selectable through the "dateStyle" front-matter field.
+const (
+	dateStyleArabic  = "arabic"  // 2025年3月15日
+	dateStyleChinese = "chinese" // 二〇二五年三月十五日
+)
+
+// formatDate converts a recognised date to datetime(year: N, month: N, day: N),
+// otherwise returns a quoted string.
+func formatDate(date string) typst.Expr {
+	if t, ok := frontmatter.ParseDate(date); ok {
+		return typst.Datetime(t)
+	}
+	return typst.Str(date)
+}
+
+// displayDate renders a date as it is printed (成文日期), in Arabic or Chinese
+// numerals. Unrecognised dates are printed verbatim.
+func displayDate(date, style string) string {
+	t, ok := frontmatter.ParseDate(date)
+	if !ok {
+		return strings.TrimSpace(date)
+	}
+	if style == dateStyleChinese {
+		return chineseDate(t)
+	}
+	return frontmatter.FormatDate(t)
+}
+
+var chineseDigits = []rune("〇一二三四五六七八九")
+
+// chineseDate renders t as "二〇二五年三月十五日".
+func chineseDate(t time.Time) string {
+	var year strings.Builder
+	for _, d := range strconv.Itoa(t.Year()) {
+		year.WriteRune(chineseDigits[d-'0'])
+	}
+	return year.String() + "年" + chineseNumber(int(t.Month())) + "月" + chineseNumber(t.Day()) + "日"
+}
+
+// chineseUnits are the place values spelled by chineseNumber, from thousands down.
+var chineseUnits = []struct {
+	value int
+	unit  string
+}{{1000, "千"}, {100, "百"}, {10, "十"}, {1, ""}}
+
+// chineseNumber spells n in Chinese numerals as Typst's "一" numbering does:
+// 十, 十五, 二十, 一百, 一百零一, 一百一十. Numbers outside 1–9999 stay in
+// Arabic digits.
+func chineseNumber(n int) string {
+	if n < 1 || n > 9999 {
+		return strconv.Itoa(n)
+	}
+	var buf strings.Builder
+	zero := false // a zero place lies between the last digit written and the next
+	for _, u := range chineseUnits {
+		digit := n / u.value % 10
+		if digit == 0 {
+			zero = buf.Len() > 0
+			continue
+		}
+		if zero {
+			buf.WriteRune('零')
+			zero = false
+		}
+		if !(digit == 1 && u.value == 10 && n < 20) { // 十五 rather than 一十五
+			buf.WriteRune(chineseDigits[digit])
+		}
+		buf.WriteString(u.unit)
+	}
+	return buf.String()
+}
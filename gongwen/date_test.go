@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestChineseNumber(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "一"},
+		{10, "十"},
+		{11, "十一"},
+		{20, "二十"},
+		{31, "三十一"},
+		{99, "九十九"},
+		{100, "一百"},
+		{101, "一百零一"},
+		{110, "一百一十"},
+		{1001, "一千零一"},
+		{1010, "一千零一十"},
+		{10000, "10000"},
+	}
+	for _, tt := range tests {
+		if got := chineseNumber(tt.n); got != tt.want {
+			t.Errorf("chineseNumber(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// ---------- Footnotes ----------
+
+// collectFootnotes indexes the footnote definitions that goldmark gathers into
+// a FootnoteList at the end of the document.
+func (c *converter) collectFootnotes(doc ast.Node) {
+	c.footnotes = map[int]*east.Footnote{}
+	c.footnoteSeen = map[int]bool{}
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		if child.Kind() != east.KindFootnoteList {
+			continue
+		}
+		for fn := child.FirstChild(); fn != nil; fn = fn.NextSibling() {
+			if f, ok := fn.(*east.Footnote); ok {
+				c.footnotes[f.Index] = f
+			}
+		}
+	}
+}
+
+// renderFootnoteLink renders a [^ref] reference as a Typst footnote. The first
+// reference carries the definition and a <fn-N> label; repeated references to
+// the same definition point back at that label instead of duplicating it.
+func (c *converter) renderFootnoteLink(link *east.FootnoteLink) string {
+	label := fmt.Sprintf("fn-%d", link.Index)
+	if c.footnoteSeen[link.Index] {
+		return string(typst.Inline(typst.Call("footnote", typst.Pos(typst.LabelValue(label)))))
+	}
+	fn, ok := c.footnotes[link.Index]
+	if !ok {
+		return ""
+	}
+	c.footnoteSeen[link.Index] = true
+	footnote := typst.Embed(typst.Call("footnote").Body(typst.Markup(c.renderFootnoteContent(fn))))
+	return string(footnote + typst.Markup(typst.Label(label)))
+}
+
+// renderFootnoteContent renders a footnote definition through the inline pipeline.
+func (c *converter) renderFootnoteContent(fn *east.Footnote) string {
+	var parts []string
+	for child := fn.FirstChild(); child != nil; child = child.NextSibling() {
+		var content string
+		if child.Kind() == ast.KindParagraph {
+			content = c.renderInlines(child)
+		} else {
+			content = c.renderBlock(child, false)
+		}
+		if content = strings.TrimSpace(content); content != "" {
+			parts = append(parts, content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+)
+
+// ---------- 版头 (document header) and 版记 (document footer) ----------
+
+// docYearRe matches the year part of a 发文字号 written with ASCII or other brackets,
+// e.g. "[2025]", "(2025)", "【2025】", "（2025）".
+var docYearRe = regexp.MustCompile(`[\[(（【〔](\d{4})[\])）】〕]`)
+
+// normalizeDocNumber rewrites the year brackets of a 发文字号 to the
+// 六角括号 〔〕 required by GB/T 9704.
+func normalizeDocNumber(s string) string {
+	return docYearRe.ReplaceAllString(strings.TrimSpace(s), "〔$1〕")
+}
+
+// normalizeCopyNumber pads a numeric 份号 to the six digits required by GB/T 9704.
+func normalizeCopyNumber(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) >= 6 {
+		return s
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return s
+		}
+	}
+	return strings.Repeat("0", 6-len(s)) + s
+}
+
+// classificationMark joins 密级 and 保密期限 as "秘密★1年".
+func classificationMark(level, period string) string {
+	level = strings.TrimSpace(level)
+	period = strings.TrimSpace(period)
+	if level == "" {
+		return ""
+	}
+	if period == "" {
+		return level
+	}
+	return level + "★" + period
+}
+
+// hasDocHeader reports whether any 版头 element is set in front matter.
+func (fm frontMatter) hasDocHeader() bool {
+	return fm.CopyNumber != "" || fm.Classification != "" || fm.Urgency != "" ||
+		len(fm.Issuer) > 0 || fm.DocNumber != "" || len(fm.Signers) > 0
+}
+
+// renderDocHeader generates the #doc-header call defined in template_head.typ.
+func renderDocHeader(fm frontMatter) string {
+	if !fm.hasDocHeader() {
+		return ""
+	}
+	header := typst.Call("doc-header",
+		typst.Named("copy-number", typst.StrOrNone(normalizeCopyNumber(fm.CopyNumber))),
+		typst.Named("classification", typst.StrOrNone(classificationMark(fm.Classification, fm.ClassificationPeriod))),
+		typst.Named("urgency", typst.StrOrNone(fm.Urgency)),
+		typst.Named("issuer", typst.StrArray(fm.Issuer)),
+		typst.Named("doc-number", typst.StrOrNone(normalizeDocNumber(fm.DocNumber))),
+		typst.Named("signers", typst.StrArray(fm.Signers)),
+	)
+	return string(typst.Embed(header)) + "\n\n"
+}
+
+// printedDate formats 印发日期, which GB/T 9704 always prints in Arabic numerals
+// with the full year and no zero padding ("2025年3月15日").
+func printedDate(date string) string {
+	return displayDate(date, dateStyleArabic)
+}
+
+// hasDocFooter reports whether any 版记 element is set in front matter.
+func (fm frontMatter) hasDocFooter() bool {
+	return len(fm.CC) > 0 || fm.PrintedBy != "" || fm.PrintedDate != ""
+}
+
+// renderDocFooter generates the #doc-footer call defined in template_head.typ.
+func renderDocFooter(fm frontMatter) string {
+	if !fm.hasDocFooter() {
+		return ""
+	}
+	footer := typst.Call("doc-footer",
+		typst.Named("cc", typst.StrArray(fm.CC)),
+		typst.Named("printed-by", typst.StrOrNone(fm.PrintedBy)),
+		typst.Named("printed-date", typst.StrOrNone(printedDate(fm.PrintedDate))),
+	)
+	return "\n" + string(typst.Embed(footer)) + "\n"
+}
+
+// ---------- 主送机关 and 附件说明 ----------
+
+// renderRecipients generates the 主送机关 line placed after the title.
+func renderRecipients(fm frontMatter) string {
+	if len(fm.Recipients) == 0 {
+		return ""
+	}
+	items := make([]string, len(fm.Recipients))
+	for i, r := range fm.Recipients {
+		items[i] = strings.TrimRight(r, "：:、，,")
+	}
+	return string(typst.Embed(typst.Call("recipients", typst.Pos(typst.StrArray(items))))) + "\n\n"
+}
+
+// renderAttachmentNote generates the numbered 附件说明 placed before the signature.
+// GB/T 9704 forbids punctuation after attachment titles, so it is stripped here.
+func renderAttachmentNote(fm frontMatter) string {
+	if len(fm.Attachments) == 0 {
+		return ""
+	}
+	items := make([]string, len(fm.Attachments))
+	for i, a := range fm.Attachments {
+		items[i] = strings.TrimRight(a, "。．.；;，,：:")
+	}
+	return "\n" + string(typst.Embed(typst.Call("attachment-note", typst.Pos(typst.StrArray(items))))) + "\n"
+}
@@ -11,12 +11,14 @@ import (
 	"unicode"
 
 	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
 	"github.com/Presto-io/presto-official-templates/internal/typst"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
-	"gopkg.in/yaml.v3"
 )
 
 //go:embed template_head.typ
@@ -31,101 +33,102 @@ var exampleMD string
 // ---------- YAML front-matter ----------
 
 type frontMatter struct {
-	Title     string
-	Author    string // joined with "、"
-	Date      string // raw string from YAML
-	Signature bool
+	Title       string
+	Author      string      // joined with "、"
+	Signatories []signatory // one per issuing authority, for the signature block
+	Date        string      // raw string from YAML
+	DateStyle   string      // dateStyleArabic or dateStyleChinese
+	Signature   bool
+	TableStyle  string // tableStyleGrid or tableStyleThreeLine
+	BodyLine    int    // input line on which the Markdown body starts
+
+	// 版头
+	CopyNumber           string   // 份号
+	Classification       string   // 密级
+	ClassificationPeriod string   // 保密期限
+	Urgency              string   // 紧急程度
+	Issuer               []string // 发文机关标志, one line per issuing authority
+	DocNumber            string   // 发文字号
+	Signers              []string // 签发人
+
+	// 主送机关 and 附件说明
+	Recipients  []string
+	Attachments []string // attachment titles, in order
+
+	// 版记
+	CC          []string // 抄送机关
+	PrintedBy   string   // 印发机关
+	PrintedDate string   // 印发日期, raw string from YAML
 }
 
-// parseFrontMatter splits "---" delimited YAML from body and returns metadata + body.
-func parseFrontMatter(input string) (frontMatter, string) {
-	var fm frontMatter
-	fm.Title = "请输入文字"
-	fm.Author = "请输入文字"
-
-	// Normalise line endings
-	input = strings.ReplaceAll(input, "\r\n", "\n")
-
-	if !strings.HasPrefix(input, "---") {
-		return fm, input
-	}
-
-	// Find closing ---
-	rest := input[3:]
-	if len(rest) > 0 && rest[0] == '\n' {
-		rest = rest[1:]
-	}
-	idx := strings.Index(rest, "\n---")
-	if idx < 0 {
-		return fm, input
-	}
-	yamlBlock := rest[:idx]
-	body := rest[idx+4:] // skip "\n---"
-	if len(body) > 0 && body[0] == '\n' {
-		body = body[1:]
-	}
+// signatory is one issuing authority in the signature block.
+type signatory struct {
+	Name string
+	Seal bool // reserve space for the authority's seal (印章)
+}
 
-	// Parse YAML into a generic map
-	var raw map[string]interface{}
-	if err := yaml.Unmarshal([]byte(yamlBlock), &raw); err != nil {
-		return fm, body
+// parseSignatory reads an author entry: either a plain name or {name, seal}.
+func parseSignatory(v interface{}) signatory {
+	if m, ok := v.(map[string]interface{}); ok {
+		name, _ := m["name"].(string)
+		seal, _ := m["seal"].(bool)
+		return signatory{Name: strings.TrimSpace(name), Seal: seal}
 	}
+	name, _ := v.(string)
+	return signatory{Name: strings.TrimSpace(name)}
+}
 
-	// title
-	if v, ok := raw["title"]; ok {
-		fm.Title = fmt.Sprintf("%v", v)
-	}
+// frontMatterSchema is the frontmatterSchema of the embedded manifest, which
+// supplies the defaults and checks for parseFrontMatter.
+var frontMatterSchema = frontmatter.MustParseSchema(manifestJSON)
 
-	// author: string or list of strings → join with "、"
-	if v, ok := raw["author"]; ok {
-		switch a := v.(type) {
-		case string:
-			fm.Author = a
-		case []interface{}:
-			parts := make([]string, 0, len(a))
-			for _, item := range a {
-				parts = append(parts, fmt.Sprintf("%v", item))
+// parseFrontMatter splits "---" delimited YAML from body and returns metadata + body.
+// The front matter is validated against manifest.json; invalid values are
+// reported and replaced by the schema defaults.
+func parseFrontMatter(input string) (frontMatter, string, []cli.Diagnostic) {
+	doc, diags := frontmatter.Parse(input, frontMatterSchema)
+
+	fm := frontMatter{
+		Title:     doc.String("title"),
+		Date:      doc.String("date"),
+		DateStyle: doc.String("dateStyle"),
+		Signature: doc.Bool("signature"),
+		BodyLine:  doc.BodyLine,
+
+		TableStyle:           doc.String("tableStyle"),
+		CopyNumber:           doc.String("copyNumber"),
+		Classification:       doc.String("classification"),
+		ClassificationPeriod: doc.String("classificationPeriod"),
+		Urgency:              doc.String("urgency"),
+		Issuer:               doc.Strings("issuer"),
+		DocNumber:            doc.String("docNumber"),
+		Signers:              doc.Strings("signer"),
+		Recipients:           doc.Strings("recipients"),
+		Attachments:          doc.Strings("attachments"),
+		CC:                   doc.Strings("cc"),
+		PrintedBy:            doc.String("printedBy"),
+		PrintedDate:          doc.String("printedDate"),
+	}
+
+	// author: string, list of strings or list of {name, seal} → join with "、"
+	switch a := doc.Values["author"].(type) {
+	case string:
+		fm.Author = a
+		fm.Signatories = []signatory{{Name: a}}
+	case []interface{}:
+		parts := make([]string, 0, len(a))
+		for _, item := range a {
+			s := parseSignatory(item)
+			if s.Name == "" {
+				continue
 			}
-			fm.Author = strings.Join(parts, "、")
+			fm.Signatories = append(fm.Signatories, s)
+			parts = append(parts, s.Name)
 		}
+		fm.Author = strings.Join(parts, "、")
 	}
 
-	// date
-	if v, ok := raw["date"]; ok {
-		fm.Date = fmt.Sprintf("%v", v)
-	}
-
-	// signature: bool or string
-	if v, ok := raw["signature"]; ok {
-		switch s := v.(type) {
-		case bool:
-			fm.Signature = s
-		case string:
-			lower := strings.ToLower(s)
-			fm.Signature = lower == "true" || lower == "yes"
-		}
-	}
-
-	return fm, body
-}
-
-var dateRe = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
-
-// formatDate converts "YYYY-MM-DD" to datetime(year: N, month: N, day: N),
-// otherwise returns a quoted string.
-func formatDate(date string) string {
-	if date == "" {
-		return `""`
-	}
-	m := dateRe.FindStringSubmatch(date)
-	if m != nil {
-		// Strip leading zeros for month/day
-		year := m[1]
-		month := strings.TrimLeft(m[2], "0")
-		day := strings.TrimLeft(m[3], "0")
-		return fmt.Sprintf("datetime(\n  year: %s,\n  month: %s,\n  day: %s,\n)", year, month, day)
-	}
-	return fmt.Sprintf(`"%s"`, typst.EscapeString(date))
+	return fm, doc.Body, diags
 }
 
 // ---------- Punctuation conversion ----------
@@ -209,8 +212,45 @@ func preprocessBody(body string) string {
 
 type converter struct {
 	source        []byte
+	bodyLine      int              // input line on which source starts
+	diags         []cli.Diagnostic // problems found while rendering
 	figureCounter int
+	tableCounter  int
+	tableStyle    string
 	hasSeenHeader bool
+
+	footnotes    map[int]*east.Footnote // footnote definitions by index
+	footnoteSeen map[int]bool           // footnotes whose definition was already emitted
+
+	headingCounters [6]int            // heading numbers by level, mirroring template_head.typ
+	refTargets      map[string]string // reference text by label ID, e.g. "fig:plan" → "图1"
+	referenced      map[string]bool   // label IDs used by {@…} references
+	labels          map[string]bool   // labels already attached in this pass
+
+	attachmentTotal   int      // number of attachment headings in the document
+	attachmentCounter int      // attachments rendered so far
+	attachmentTitles  []string // plain titles of rendered attachments
+	attachmentStart   int      // output offset of the first attachment, -1 if none
+}
+
+// line returns the input line of byte offset off in source.
+func (c *converter) line(off int) int {
+	return c.bodyLine + strings.Count(string(c.source[:off]), "\n")
+}
+
+// blockLine returns the input line on which the block containing n starts.
+func (c *converter) blockLine(n ast.Node) int {
+	for ; n != nil; n = n.Parent() {
+		if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			return c.line(n.Lines().At(0).Start)
+		}
+	}
+	return c.bodyLine
+}
+
+// warnf reports a problem found in the body at the given input line.
+func (c *converter) warnf(line int, code, format string, args ...interface{}) {
+	c.diags = append(c.diags, cli.Warningf(line, 1, code, format, args...))
 }
 
 // nodeText extracts raw text from an inline node and its children.
@@ -265,10 +305,35 @@ func (c *converter) plainText(n ast.Node) string {
 }
 
 // renderInlines renders inline children of a node to Typst.
+// Consecutive text nodes are rendered as one run, since goldmark splits text at
+// delimiter characters such as "_" that may occur inside a {@ref}.
 func (c *converter) renderInlines(n ast.Node) string {
 	var buf strings.Builder
-	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
-		buf.WriteString(c.renderInline(child))
+	for child := n.FirstChild(); child != nil; {
+		if child.Kind() != ast.KindText {
+			buf.WriteString(c.renderInline(child))
+			child = child.NextSibling()
+			continue
+		}
+		var raw strings.Builder
+		hardBreak := false
+		start := child.(*ast.Text).Segment.Start
+		for child != nil && child.Kind() == ast.KindText {
+			t := child.(*ast.Text)
+			raw.Write(t.Segment.Value(c.source))
+			if t.SoftLineBreak() {
+				raw.WriteByte('\n')
+			}
+			child = child.NextSibling()
+			if t.HardLineBreak() {
+				hardBreak = true
+				break
+			}
+		}
+		buf.WriteString(c.renderText(raw.String(), start))
+		if hardBreak {
+			buf.WriteString(" \\\n")
+		}
 	}
 	return buf.String()
 }
@@ -279,7 +344,7 @@ func (c *converter) renderInline(n ast.Node) string {
 	case ast.KindText:
 		t := n.(*ast.Text)
 		raw := string(t.Segment.Value(c.source))
-		result := typst.EscapeContent(convertPunctuation(raw))
+		result := c.renderText(raw, t.Segment.Start)
 		if t.SoftLineBreak() {
 			result += "\n"
 		}
@@ -299,29 +364,39 @@ func (c *converter) renderInline(n ast.Node) string {
 				code.Write(child.(*ast.Text).Segment.Value(c.source))
 			}
 		}
-		return "`" + code.String() + "`"
+		// Pass the code as a string literal so that no backtick run can end the raw
+		// element early; ";" stops following text from continuing the expression.
+		return string(typst.Inline(typst.Raw(code.String(), "", false)))
 
 	case ast.KindEmphasis:
 		em := n.(*ast.Emphasis)
 		inner := c.renderInlines(n)
+		fn := "emph"
 		if em.Level == 2 {
-			return "#strong[" + inner + "]"
+			fn = "strong"
 		}
-		return "#emph[" + inner + "]"
+		return string(typst.Inline(typst.Call(fn).Body(typst.Markup(inner))))
 
 	case ast.KindLink:
 		link := n.(*ast.Link)
 		inner := c.renderInlines(n)
-		return fmt.Sprintf(`#link("%s")[%s]`, typst.EscapeString(string(link.Destination)), inner)
+		call := typst.Call("link", typst.Pos(typst.Str(string(link.Destination)))).Body(typst.Markup(inner))
+		return string(typst.Inline(call))
 
 	case ast.KindAutoLink:
 		al := n.(*ast.AutoLink)
 		url := string(al.URL(c.source))
-		return fmt.Sprintf(`#link("%s")`, typst.EscapeString(url))
+		return string(typst.Inline(typst.Call("link", typst.Pos(typst.Str(url)))))
 
 	case ast.KindImage:
 		return ""
 
+	case east.KindFootnoteLink:
+		return c.renderFootnoteLink(n.(*east.FootnoteLink))
+
+	case east.KindFootnoteBacklink:
+		return ""
+
 	case ast.KindRawHTML:
 		return ""
 
@@ -342,203 +417,121 @@ func (c *converter) collectImages(para ast.Node) []*ast.Image {
 }
 
 // renderSingleImage generates Typst figure code for a single image.
-func (c *converter) renderSingleImage(img *ast.Image) string {
-	c.figureCounter++
+// Attributes written after the image ({width=60% #fig:plan .unnumbered}) select
+// the size, caption, alignment, numbering and label; without any, the image is
+// fitted into 13.4cm and captioned with its file name.
+func (c *converter) renderSingleImage(img *ast.Image, attrs attributes) string {
 	path := string(img.Destination)
 	filename := filepath.Base(path)
-	caption := strings.TrimSuffix(filename, filepath.Ext(filename))
-	escapedPath := typst.EscapeString(path)
-	escapedCaption := typst.EscapeContent(caption)
-
-	return fmt.Sprintf(`#figure(
-  context {
-    let img = image("%s")
-    let img-size = measure(img)
-    let x = img-size.width
-    let y = img-size.height
-    let max-size = 13.4cm
-
-    let new-x = x
-    let new-y = y
-
-    if x > max-size {
-      let scale = max-size / x
-      new-x = max-size
-      new-y = y * scale
-    }
-
-    if new-y > max-size {
-      let scale = max-size / new-y
-      new-x = new-x * scale
-      new-y = max-size
-    }
-
-    image("%s", width: new-x, height: new-y)
-  },
-  caption: [%s],
-) <fig-%d>
-`, escapedPath, escapedPath, escapedCaption, c.figureCounter)
-}
-
-// renderMultiImage generates Typst code for multiple images in one paragraph.
-func (c *converter) renderMultiImage(images []*ast.Image) string {
-	type imgInfo struct {
-		path, caption, alt string
-		figNum             int
+	caption := typst.Text(strings.TrimSuffix(filename, filepath.Ext(filename)))
+	if !attrs.isEmpty() {
+		if text := attrs.Values["caption"]; text != "" {
+			caption = typst.Text(convertPunctuation(text))
+		} else if alt := strings.TrimSpace(c.plainText(img)); alt != "" {
+			caption = typst.Text(convertPunctuation(alt))
+		}
 	}
 
-	var infos []imgInfo
-	isSubfigure := false
+	numbered := !attrs.hasClass("unnumbered")
+	var label string
+	if numbered {
+		c.figureCounter++
+		id := attrs.ID
+		if id == "" {
+			id = fmt.Sprintf("fig-%d", c.figureCounter)
+		}
+		label = c.addTarget(id, fmt.Sprintf("图%d", c.figureCounter))
+	} else {
+		label = c.addTarget(attrs.ID, c.plainText(img))
+	}
+
+	var body typst.Expr
+	line := c.blockLine(img)
+	width, height := c.dimension(attrs, "width", line), c.dimension(attrs, "height", line)
+	if width != nil || height != nil {
+		call := typst.Call("image", typst.Pos(typst.Str(path)))
+		if width != nil {
+			call.Arg(typst.Named("width", width))
+		}
+		if height != nil {
+			call.Arg(typst.Named("height", height))
+		}
+		if width != nil && height != nil {
+			call.Arg(typst.Named("fit", typst.Str("contain")))
+		}
+		body = call
+	} else {
+		body = typst.Call("fit-image", typst.Pos(typst.Str(path)))
+	}
+
+	figure := typst.Call("figure",
+		typst.Pos(body),
+		typst.Named("caption", typst.Content(caption)),
+	)
+	if !numbered {
+		figure.Arg(typst.Named("numbering", typst.None))
+	}
+
+	var buf strings.Builder
+	align := attrs.Values["align"]
+	if align != "left" && align != "right" {
+		align = ""
+	}
+	if align != "" {
+		buf.WriteString(string(typst.Embed(typst.Call("figure-align.update", typst.Pos(typst.Ident(align))))) + "\n")
+	}
+	buf.WriteString(string(typst.Embed(figure).WithLabel(label)) + "\n")
+	if align != "" {
+		buf.WriteString(string(typst.Embed(typst.Call("figure-align.update", typst.Pos(typst.Ident("center"))))) + "\n")
+	}
+	return buf.String()
+}
 
+// imageAttributes parses the {…} attribute block written right after img.
+func (c *converter) imageAttributes(img *ast.Image) attributes {
+	var buf strings.Builder
+	for n := img.NextSibling(); n != nil; n = n.NextSibling() {
+		buf.WriteString(c.plainText(n))
+	}
+	attrs, _ := parseAttributes(buf.String())
+	return attrs
+}
+
+// renderMultiImage generates a #multi-image call (see template_head.typ) for
+// multiple images in one paragraph. If any image has alt text, the images form
+// one figure of sub-figures captioned with the first alt text; otherwise each
+// image is a separately numbered figure.
+func (c *converter) renderMultiImage(images []*ast.Image) string {
+	isSubfigure := false
 	for _, img := range images {
-		alt := c.plainText(img)
-		if alt != "" {
+		if c.plainText(img) != "" {
 			isSubfigure = true
 			break
 		}
 	}
 
-	if isSubfigure {
-		c.figureCounter++
-	}
-
-	for _, img := range images {
+	items := make([]typst.Expr, len(images))
+	for i, img := range images {
 		path := string(img.Destination)
 		filename := filepath.Base(path)
-		caption := strings.TrimSuffix(filename, filepath.Ext(filename))
-		alt := c.plainText(img)
-		figNum := 0
-		if !isSubfigure {
-			c.figureCounter++
-			figNum = c.figureCounter
-		}
-		infos = append(infos, imgInfo{path, caption, alt, figNum})
-	}
-
-	var pathsStr, captionsStr, altsStr []string
-	mainCaption := ""
-	for _, info := range infos {
-		pathsStr = append(pathsStr, fmt.Sprintf(`"%s"`, typst.EscapeString(info.path)))
-		captionsStr = append(captionsStr, fmt.Sprintf(`"%s"`, typst.EscapeString(info.caption)))
-		altsStr = append(altsStr, fmt.Sprintf(`"%s"`, typst.EscapeString(info.alt)))
-	}
-	if isSubfigure && len(infos) > 0 {
-		mainCaption = infos[0].alt
-	}
-
-	return fmt.Sprintf(`
-#context {
-  let paths = (%s)
-  let captions = (%s)
-  let alts = (%s)
-
-  let is_subfigure = %s
-  let main_caption = "%s"
-
-  let gap = 0.3cm
-  let max-width = 13.4cm
-  let min-height = 6cm
-
-  let sizes = paths.zip(captions).zip(alts).map(item => {
-    let p = item.at(0).at(0)
-    let c = item.at(0).at(1)
-    let alt = item.at(1)
-    let img = image(p)
-    let s = measure(img)
-    (width: s.width, height: s.height, path: p, caption: c, alt: alt, ratio: s.width / s.height)
-  })
-
-  let calc-row-height(imgs, total-width) = {
-    let ratio-sum = imgs.map(i => i.ratio).sum()
-    total-width / ratio-sum
-  }
-
-  let rows = ()
-
-  if is_subfigure {
-    rows.push(sizes)
-  } else {
-    let remaining = sizes
-
-    while remaining.len() > 0 {
-      let row = ()
-      let found = false
-
-      for n in range(1, remaining.len() + 1) {
-        let candidate = remaining.slice(0, n)
-        let gaps = (n - 1) * gap
-        let available-width = max-width - gaps
-        let row-h = calc-row-height(candidate, available-width)
-
-        if row-h < min-height and n > 1 {
-          row = remaining.slice(0, n - 1)
-          remaining = remaining.slice(n - 1)
-          found = true
-          break
-        }
-      }
-
-      if not found {
-        row = remaining
-        remaining = ()
-      }
-
-      rows.push(row)
-    }
-  }
-
-  let render-rows(rows) = {
-    for row in rows {
-      let n = row.len()
-      let gaps = (n - 1) * gap
-      let available-width = max-width - gaps
-      let row-height = calc-row-height(row, available-width)
-
-      if row-height > max-width {
-        row-height = max-width
-      }
-
-      align(center, grid(
-        columns: n,
-        gutter: gap,
-        ..row.enumerate().map(item => {
-          let i = item.at(0)
-          let img-data = item.at(1)
-          let w = row-height * img-data.ratio
-
-          if is_subfigure {
-             let sub-label = numbering("a", i + 1)
-             let sub-text = [ (#sub-label) #img-data.caption ]
-
-             v(0.5em)
-             align(center, block({
-               image(img-data.path, width: w, height: row-height)
-               align(center, text(font: FONT_FS, size: zh(3))[#sub-text])
-             }))
-          } else {
-             figure(
-               image(img-data.path, width: w, height: row-height),
-               caption: [ #img-data.caption ]
-             )
-          }
-        })
-      ))
-      if is_subfigure { v(0.5em) } else { v(0.3em) }
-    }
-  }
-
-  if is_subfigure {
-    figure(
-      context { render-rows(rows) },
-      caption: [ #main_caption ]
-    )
-  } else {
-    render-rows(rows)
-  }
-}
-
-`, strings.Join(pathsStr, ", "), strings.Join(captionsStr, ", "),
-		strings.Join(altsStr, ", "), strconv.FormatBool(isSubfigure), typst.EscapeString(mainCaption))
+		items[i] = typst.Dict(
+			typst.Named("path", typst.Str(path)),
+			typst.Named("caption", typst.Str(strings.TrimSuffix(filename, filepath.Ext(filename)))),
+			typst.Named("alt", typst.Str(c.plainText(img))),
+		)
+	}
+
+	call := typst.Call("multi-image", typst.Pos(typst.Array(items...)))
+	if isSubfigure {
+		c.figureCounter++
+		call.Arg(
+			typst.Named("subfigure", typst.Bool(true)),
+			typst.Named("caption", typst.Str(c.plainText(images[0]))),
+		)
+	} else {
+		c.figureCounter += len(images)
+	}
+	return "\n" + string(typst.Embed(call)) + "\n\n"
 }
 
 // vMarkerRe matches {v} or {v:N}
@@ -554,20 +547,20 @@ func processMarker(text string) (string, bool) {
 		}
 		var lines []string
 		for i := 0; i < count; i++ {
-			lines = append(lines, "#linebreak(justify: false)")
+			lines = append(lines, string(typst.Embed(typst.Call("linebreak", typst.Named("justify", typst.Bool(false))))))
 		}
 		return strings.Join(lines, "\n") + "\n", true
 	}
 	if text == "{pagebreak}" {
-		return "#pagebreak()\n", true
+		return string(typst.Embed(typst.Call("pagebreak"))) + "\n", true
 	}
 	if text == "{pagebreak:weak}" {
-		return "#pagebreak(weak: true)\n", true
+		return string(typst.Embed(typst.Call("pagebreak", typst.Named("weak", typst.Bool(true))))) + "\n", true
 	}
 	return "", false
 }
 
-// stripTrailingMarker checks for {.noindent} or {indent} at end of inline text.
+// stripTrailingMarker checks for {.noindent}, {indent} or {attachment} at end of inline text.
 func stripTrailingMarker(text string) (string, string) {
 	text = strings.TrimRight(text, " ")
 	if strings.HasSuffix(text, "{.noindent}") {
@@ -576,14 +569,30 @@ func stripTrailingMarker(text string) (string, string) {
 	if strings.HasSuffix(text, "{indent}") {
 		return strings.TrimRight(strings.TrimSuffix(text, "{indent}"), " "), "indent"
 	}
+	if strings.HasSuffix(text, attachmentMarker) {
+		return strings.TrimRight(strings.TrimSuffix(text, attachmentMarker), " "), "attachment"
+	}
 	return text, ""
 }
 
+// noindentRule turns off the first-line indent for the paragraphs that follow it.
+var noindentRule = typst.Set("par", typst.Named("first-line-indent", typst.Length("0pt")))
+
+// noindentBlock wraps content in a block whose paragraphs are not indented.
+func noindentBlock(content string) string {
+	inner := typst.Embed(typst.Call("block").Body(typst.Markup("\n" + content + "\n\n")))
+	return string(typst.Embed(typst.Call("block").Body(noindentRule+"\n"+inner+"\n"))) + "\n"
+}
+
 // renderParagraph renders a paragraph node to Typst.
 func (c *converter) renderParagraph(para *ast.Paragraph) string {
+	if c.isTableCaption(para) {
+		return "" // rendered as part of the table figure
+	}
+
 	images := c.collectImages(para)
 	if len(images) == 1 {
-		return c.renderSingleImage(images[0])
+		return c.renderSingleImage(images[0], c.imageAttributes(images[0]))
 	}
 	if len(images) > 1 {
 		return c.renderMultiImage(images)
@@ -603,7 +612,7 @@ func (c *converter) renderParagraph(para *ast.Paragraph) string {
 		content = strings.TrimRight(content, " \n")
 		content = strings.TrimSuffix(content, "{.noindent}")
 		content = strings.TrimRight(content, " ")
-		return "#block[#set par(first-line-indent: 0pt)\n#block[\n" + content + "\n\n]\n]\n"
+		return noindentBlock(content)
 	}
 	if marker == "indent" {
 		content = strings.TrimRight(content, " \n")
@@ -615,7 +624,7 @@ func (c *converter) renderParagraph(para *ast.Paragraph) string {
 	if !c.hasSeenHeader {
 		t := strings.TrimSpace(content)
 		if strings.HasSuffix(t, "：") || strings.HasSuffix(t, ":") {
-			return "#block[#set par(first-line-indent: 0pt)\n#block[\n" + content + "\n\n]\n]\n"
+			return noindentBlock(content)
 		}
 	}
 
@@ -627,22 +636,33 @@ func (c *converter) renderHeading(h *ast.Heading) string {
 	c.hasSeenHeader = true
 
 	if h.Level == 1 {
+		if c.isAttachmentHeading(h) {
+			return c.renderAttachmentHeading(h)
+		}
 		return ""
 	}
 
 	content := c.renderInlines(h)
+	number := c.headingNumber(h.Level)
+
+	plain, id, attrBlock := splitTrailingID(strings.TrimSpace(c.plainText(h)))
+	if attrBlock != "" {
+		content = strings.TrimRight(content, " \n")
+		content = strings.TrimSuffix(content, typst.EscapeContent(attrBlock))
+		content = strings.TrimRight(content, " ")
+	}
+	title, marker := stripTrailingMarker(plain)
+	label := c.headingLabel(h, id, number, convertPunctuation(title))
 
-	_, marker := stripTrailingMarker(strings.TrimSpace(c.plainText(h)))
 	if marker == "noindent" {
 		content = strings.TrimRight(content, " \n")
 		content = strings.TrimSuffix(content, "{.noindent}")
 		content = strings.TrimRight(content, " ")
-		prefix := strings.Repeat("=", h.Level)
-		return "#block[#set par(first-line-indent: 0pt)\n" + prefix + " " + content + "\n]\n\n"
+		heading := typst.Heading(h.Level, typst.Markup(content)).WithLabel(label)
+		return string(typst.Embed(typst.Call("block").Body(noindentRule+"\n"+heading+"\n"))) + "\n\n"
 	}
 
-	prefix := strings.Repeat("=", h.Level)
-	return prefix + " " + content + "\n\n"
+	return string(typst.Heading(h.Level, typst.Markup(content)).WithLabel(label)) + "\n\n"
 }
 
 // renderList renders a list node to Typst.
@@ -707,8 +727,12 @@ func isHTMLComment(n ast.Node, source []byte, keyword string) bool {
 func (c *converter) renderDocument(doc ast.Node) string {
 	var buf strings.Builder
 	child := doc.FirstChild()
+	c.attachmentStart = -1
 
 	for child != nil {
+		if c.attachmentStart < 0 && c.isAttachmentHeading(child) {
+			c.attachmentStart = buf.Len()
+		}
 		if isHTMLComment(child, c.source, "noindent-start") {
 			child = child.NextSibling()
 			var innerBuf strings.Builder
@@ -719,10 +743,8 @@ func (c *converter) renderDocument(doc ast.Node) string {
 			if child != nil {
 				child = child.NextSibling()
 			}
-			inner := innerBuf.String()
-			buf.WriteString("#block[#set par(first-line-indent: 0pt)\n#block[\n")
-			buf.WriteString(inner)
-			buf.WriteString("]\n]\n")
+			inner := typst.Embed(typst.Call("block").Body(typst.Markup("\n" + innerBuf.String())))
+			buf.WriteString(string(typst.Embed(typst.Call("block").Body(noindentRule+"\n"+inner+"\n"))) + "\n")
 		} else {
 			buf.WriteString(c.renderBlock(child, false))
 			child = child.NextSibling()
@@ -742,15 +764,19 @@ func (c *converter) renderBlock(n ast.Node, inNoindent bool) string {
 	case ast.KindList:
 		content := c.renderList(n.(*ast.List))
 		if inNoindent {
-			return "#block[#set par(first-line-indent: 0pt)\n" + content + "]\n"
+			return string(typst.Embed(typst.Call("block").Body(noindentRule+"\n"+typst.Markup(content)))) + "\n"
 		}
 		return content
 	case ast.KindFencedCodeBlock, ast.KindCodeBlock:
 		return c.renderCodeBlock(n)
 	case ast.KindThematicBreak:
-		return "#line(length: 100%)\n\n"
+		return string(typst.Embed(typst.Call("line", typst.Named("length", typst.Length("100%"))))) + "\n\n"
 	case ast.KindBlockquote:
 		return c.renderBlockquote(n)
+	case east.KindTable:
+		return c.renderTable(n.(*east.Table))
+	case east.KindFootnoteList:
+		return "" // definitions are rendered at their references
 	case ast.KindHTMLBlock:
 		return ""
 	default:
@@ -780,10 +806,9 @@ func (c *converter) renderCodeBlock(n ast.Node) string {
 		}
 	}
 
-	if lang != "" {
-		return "```" + lang + "\n" + code + "```\n\n"
-	}
-	return "```\n" + code + "```\n\n"
+	// Emitted as a raw() call with a string literal: a fenced block would be
+	// terminated by any ``` inside the code.
+	return string(typst.Embed(typst.Raw(strings.TrimSuffix(code, "\n"), lang, true))) + "\n\n"
 }
 
 // renderBlockquote renders a blockquote.
@@ -792,77 +817,141 @@ func (c *converter) renderBlockquote(n ast.Node) string {
 	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
 		content := c.renderBlock(child, false)
 		for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
-			buf.WriteString("#quote[" + line + "]\n")
+			buf.WriteString(string(typst.Embed(typst.Call("quote").Body(typst.Markup(line)))) + "\n")
 		}
 	}
 	buf.WriteString("\n")
 	return buf.String()
 }
 
+// renderedBody is the Typst output of the Markdown body, split at the first attachment.
+type renderedBody struct {
+	Main             string
+	Attachments      string
+	AttachmentTitles []string
+	Diagnostics      []cli.Diagnostic
+}
+
 // convertBody parses markdown body and renders to Typst.
-func convertBody(body string) string {
+func convertBody(fm frontMatter, body string) renderedBody {
 	body = preprocessBody(body)
 	source := []byte(body)
 
 	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.Table,
+			extension.Footnote,
+		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
 	)
 	doc := md.Parser().Parse(text.NewReader(source))
 
-	conv := &converter{source: source}
-	return conv.renderDocument(doc)
+	newConverter := func() *converter {
+		conv := &converter{
+			source:     source,
+			bodyLine:   fm.BodyLine,
+			tableStyle: fm.TableStyle,
+			refTargets: map[string]string{},
+			referenced: map[string]bool{},
+			labels:     map[string]bool{},
+		}
+		for _, m := range refRe.FindAllStringSubmatch(body, -1) {
+			conv.referenced[m[1]] = true
+		}
+		conv.attachmentTotal = conv.countAttachments(doc)
+		conv.collectFootnotes(doc)
+		return conv
+	}
+
+	// A first pass numbers figures, tables and headings so that references
+	// resolve regardless of whether they precede their target.
+	first := newConverter()
+	first.renderDocument(doc)
+
+	conv := newConverter()
+	conv.refTargets = first.refTargets
+	out := conv.renderDocument(doc)
+
+	if conv.attachmentStart < 0 {
+		return renderedBody{Main: out, Diagnostics: conv.diags}
+	}
+	return renderedBody{
+		Main:             out[:conv.attachmentStart],
+		Attachments:      out[conv.attachmentStart:],
+		AttachmentTitles: conv.attachmentTitles,
+		Diagnostics:      conv.diags,
+	}
 }
 
-// convert takes parsed front-matter and markdown body, returns full .typ output.
-func convert(fm frontMatter, body string) string {
+// renderTitle generates the level-1 title heading; "|" in the title starts a new line.
+func renderTitle(title string) typst.Markup {
+	var lines []string
+	for _, part := range strings.Split(title, "|") {
+		lines = append(lines, string(typst.Text(strings.TrimSpace(part))))
+	}
+	return typst.Heading(1, typst.Markup(strings.Join(lines, string(typst.Inline(typst.Call("linebreak"))))))
+}
+
+// convert takes parsed front-matter and markdown body, returns full .typ output
+// and the problems found in the body.
+func convert(fm frontMatter, body string) (string, []cli.Diagnostic) {
 	var out strings.Builder
 
 	out.WriteString(templateHead)
-	fmt.Fprintf(&out, "#let autoTitle = \"%s\"\n\n", typst.EscapeString(fm.Title))
-	fmt.Fprintf(&out, "#let autoAuthor = \"%s\"\n\n", typst.EscapeString(fm.Author))
-	fmt.Fprintf(&out, "#let autoDate = %s\n\n", formatDate(fm.Date))
-
-	out.WriteString(`#set document(
-  title: autoTitle.replace("|", " "),
-  author: autoAuthor,
-  keywords: "工作总结, 年终报告",
-  date: auto,
-)
-
-= #autoTitle.split("|").map(s => s.trim()).join(linebreak())
-
-`)
+	for _, let := range []typst.Markup{
+		typst.Let("autoTitle", typst.Str(fm.Title)),
+		typst.Let("autoAuthor", typst.Str(fm.Author)),
+		typst.Let("autoDate", formatDate(fm.Date)),
+		typst.Let("autoDateText", typst.Str(displayDate(fm.Date, fm.DateStyle))),
+	} {
+		out.WriteString(string(let) + "\n\n")
+	}
+
+	out.WriteString(string(typst.Set("document",
+		typst.Named("title", typst.Str(strings.ReplaceAll(fm.Title, "|", " "))),
+		typst.Named("author", typst.Ident("autoAuthor")),
+		typst.Named("keywords", typst.Str("工作总结, 年终报告")),
+		typst.Named("date", typst.Auto),
+	)) + "\n\n")
+	out.WriteString(renderDocHeader(fm))
+	out.WriteString(string(renderTitle(fm.Title)) + "\n\n")
 
 	if !fm.Signature {
-		out.WriteString("#name(autoAuthor)\n")
+		out.WriteString(string(typst.Embed(typst.Call("name", typst.Pos(typst.Ident("autoAuthor"))))) + "\n")
 	}
 	out.WriteString("\n")
+	out.WriteString(renderRecipients(fm))
+
+	rendered := convertBody(fm, body)
+	if len(fm.Attachments) == 0 {
+		fm.Attachments = rendered.AttachmentTitles
+	}
 
-	out.WriteString(convertBody(body))
+	out.WriteString(rendered.Main)
+	out.WriteString(renderAttachmentNote(fm))
 
 	if fm.Signature {
-		out.WriteString(`
-#v(18pt)
-#align(right, block[
-  #set align(center)
-  #autoAuthor \
-  #autoDate.display(
-    "[year]年[month padding:none]月[day padding:none]日",
-  )
-])
-`)
+		out.WriteString(renderSignatureBlock(fm))
 	}
 
-	return out.String()
+	if rendered.Attachments != "" {
+		out.WriteString("\n")
+		out.WriteString(rendered.Attachments)
+	}
+
+	out.WriteString(renderDocFooter(fm))
+
+	return out.String(), rendered.Diagnostics
 }
 
 // ---------- CLI ----------
 
 func main() {
-	cli.Run(manifestJSON, exampleMD, func(input string) string {
-		fm, body := parseFrontMatter(input)
-		return convert(fm, body)
+	cli.Run(manifestJSON, exampleMD, func(input string) (string, []cli.Diagnostic) {
+		fm, body, diags := parseFrontMatter(input)
+		out, bodyDiags := convert(fm, body)
+		return out, append(diags, bodyDiags...)
 	})
 }
@@ -0,0 +1,28 @@
+package main
+
+import "github.com/Presto-io/presto-official-templates/internal/typst"
+
+// ---------- Signature block (发文机关署名 and 成文日期) ----------
+
+// renderSignatureBlock generates the #signature-block call defined in template_head.typ,
+// laying out every signatory per GB/T 9704 §7.3.5. Without signatories the
+// author is signed alone.
+func renderSignatureBlock(fm frontMatter) string {
+	signatories := fm.Signatories
+	if len(signatories) == 0 {
+		signatories = []signatory{{Name: fm.Author}}
+	}
+	signers := make([]typst.Expr, len(signatories))
+	for i, s := range signatories {
+		signers[i] = typst.Dict(
+			typst.Named("name", typst.Str(s.Name)),
+			typst.Named("seal", typst.Bool(s.Seal)),
+		)
+	}
+	block := typst.Call("signature-block",
+		typst.Pos(typst.Array(signers...)),
+		typst.Pos(typst.Ident("autoDateText")),
+	)
+	space := typst.Call("v", typst.Pos(typst.Length("18pt")))
+	return "\n" + string(typst.Embed(space)) + "\n" + string(typst.Embed(block)) + "\n"
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// ---------- GFM tables ----------
+
+// Table styles selectable through the "tableStyle" front-matter field.
+const (
+	tableStyleGrid      = "grid"       // 全框线表格
+	tableStyleThreeLine = "three-line" // 三线表
+)
+
+// Table captions follow Pandoc: a paragraph "Table: 标题" (or "表：标题") directly
+// before or after the table, or ": 标题" directly after it. A leading caption needs
+// the explicit prefix, since an ordinary paragraph may well start with a colon.
+// A trailing {#tab:id} in the caption sets the label used by {@tab:id} references.
+var (
+	tableCaptionPrefixes      = []string{"Table:", "Table：", "表：", "表:"}
+	tableCaptionAfterPrefixes = append([]string{":", "："}, tableCaptionPrefixes...)
+)
+
+// tableCaptionText returns the caption text if para is written as a table caption
+// using one of prefixes.
+func (c *converter) tableCaptionText(para ast.Node, prefixes []string) (string, bool) {
+	if para == nil || para.Kind() != ast.KindParagraph {
+		return "", false
+	}
+	plain := strings.TrimSpace(c.plainText(para))
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(plain, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(plain, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// isTableCaption reports whether para is a caption paragraph attached to an adjacent table.
+func (c *converter) isTableCaption(para ast.Node) bool {
+	if prev := para.PreviousSibling(); prev != nil && prev.Kind() == east.KindTable {
+		if _, ok := c.tableCaptionText(para, tableCaptionAfterPrefixes); ok {
+			return true
+		}
+	}
+	if next := para.NextSibling(); next != nil && next.Kind() == east.KindTable {
+		if _, ok := c.tableCaptionText(next.NextSibling(), tableCaptionAfterPrefixes); ok {
+			return false // the table already has a caption after it
+		}
+		_, ok := c.tableCaptionText(para, tableCaptionPrefixes)
+		return ok
+	}
+	return false
+}
+
+// tableCaption finds the caption for table, preferring one written after the table.
+func (c *converter) tableCaption(table ast.Node) string {
+	if text, ok := c.tableCaptionText(table.NextSibling(), tableCaptionAfterPrefixes); ok {
+		return text
+	}
+	prev := table.PreviousSibling()
+	if prev != nil && c.isTableCaption(prev) {
+		text, _ := c.tableCaptionText(prev, tableCaptionPrefixes)
+		return text
+	}
+	return ""
+}
+
+// tableAlign maps a GFM column alignment to a Typst alignment. Columns without an
+// explicit ":---:" marker are centred, as is customary for 公文 tables.
+func tableAlign(a east.Alignment) typst.Expr {
+	switch a {
+	case east.AlignLeft:
+		return typst.Op("+", typst.Ident("left"), typst.Ident("horizon"))
+	case east.AlignRight:
+		return typst.Op("+", typst.Ident("right"), typst.Ident("horizon"))
+	default:
+		return typst.Op("+", typst.Ident("center"), typst.Ident("horizon"))
+	}
+}
+
+// renderTableRow renders the cells of a header or body row as Typst content blocks.
+func (c *converter) renderTableRow(row ast.Node, columns int) []typst.Arg {
+	cells := make([]typst.Arg, 0, columns)
+	for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+		content := strings.TrimSpace(c.renderInlines(cell))
+		cells = append(cells, typst.Pos(typst.Content(typst.Markup(content))))
+	}
+	// goldmark pads short rows, but guard against ragged input anyway.
+	for len(cells) < columns {
+		cells = append(cells, typst.Pos(typst.Content("")))
+	}
+	return cells[:columns]
+}
+
+// renderTable generates Typst figure code for a GFM table.
+func (c *converter) renderTable(table *east.Table) string {
+	c.tableCounter++
+	columns := len(table.Alignments)
+
+	aligns := make([]typst.Expr, columns)
+	for i, a := range table.Alignments {
+		aligns[i] = tableAlign(a)
+	}
+
+	threeLine := c.tableStyle == tableStyleThreeLine
+	thickRule := typst.Call("table.hline", typst.Named("stroke", typst.Length("1.5pt")))
+
+	grid := typst.Call("table",
+		typst.Named("columns", typst.Int(columns)),
+		typst.Named("align", typst.Array(aligns...)),
+	)
+	if threeLine {
+		grid.Arg(typst.Named("stroke", typst.None))
+	} else {
+		grid.Arg(typst.Named("stroke", typst.Length("0.5pt")))
+	}
+
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		cells := c.renderTableRow(row, columns)
+		if row.Kind() != east.KindTableHeader {
+			grid.Arg(typst.Group(cells...))
+			continue
+		}
+		if threeLine {
+			// Keep the top rule inside the header so it repeats on continuation pages.
+			header := typst.Call("table.header", typst.Pos(thickRule)).Arg(cells...)
+			grid.Arg(
+				typst.Pos(header),
+				typst.Pos(typst.Call("table.hline", typst.Named("stroke", typst.Length("0.75pt")))),
+			)
+			continue
+		}
+		grid.Arg(typst.Pos(typst.Call("table.header", cells...)))
+	}
+	if threeLine {
+		grid.Arg(typst.Pos(thickRule))
+	}
+
+	figure := typst.Call("figure",
+		typst.Pos(grid),
+		typst.Named("kind", typst.Ident("table")),
+	)
+	caption, id, _ := splitTrailingID(c.tableCaption(table))
+	if caption = strings.TrimSpace(caption); caption != "" {
+		figure.Arg(typst.Named("caption", typst.Content(typst.Text(convertPunctuation(caption)))))
+	}
+	if id == "" {
+		id = fmt.Sprintf("tab-%d", c.tableCounter)
+	}
+	label := c.addTarget(id, fmt.Sprintf("表%d", c.tableCounter))
+	return string(typst.Embed(figure).WithLabel(label)) + "\n\n"
+}
@@ -7,6 +7,7 @@
 #let FONT_FS = "STFangsong" // 仿宋
 #let FONT_KAI = "STKaiti" // 楷体
 #let FONT_SONG = "STSong" // 宋体
+#let FONT_MONO = ("DejaVu Sans Mono", FONT_SONG) // 等宽字体，中文回退宋体
 
 // 设置页面、页边距、页脚
 #set page(
@@ -28,7 +29,18 @@
     let num = str(page-num)
     let pm = text(font: FONT_SONG, size: zh(4))[— #num —] // 4 号宋体
 
-    if is-even {
+    // 版记页前有空白页的，空白页和版记页均不编排页码
+    let body-end = query(<doc-body-end>)
+    let after-body = if body-end.len() > 0 {
+      let end-page = body-end.first().location().page()
+      calc.even(end-page) and page-num > end-page
+    } else {
+      false
+    }
+
+    if after-body {
+      none
+    } else if is-even {
       align(left, [#h(1em) #pm]) // 偶数页：居左
     } else {
       align(right, [#pm #h(1em)]) // 奇数页：居右
@@ -53,29 +65,194 @@
   spacing: 15.6pt, // 段间距
 )
 
+// 代码：等宽字体，不缩进；代码块 5 号字，浅色边框与底色，适用于技术类附件
+#show raw: set text(font: FONT_MONO)
+#show raw: set par(first-line-indent: 0pt, justify: false)
+#show raw.where(block: true): set text(size: zh(5))
+#show raw.where(block: true): it => block(
+  width: 100%,
+  inset: (x: 8pt, y: 6pt),
+  radius: 2pt,
+  stroke: 0.5pt + luma(190),
+  fill: luma(248),
+  align(left, it),
+)
+
+// 脚注：4 号仿宋，带圈数字编号，不缩进
+#set footnote(numbering: "①")
+#show footnote.entry: set text(font: FONT_FS, size: zh(4))
+#show footnote.entry: set par(first-line-indent: 0pt, leading: 0.65em)
+
 // 计数器设置
 #let h2-counter = counter("h2")
 #let h3-counter = counter("h3")
 #let h4-counter = counter("h4")
 #let h5-counter = counter("h5")
 
-// 图片样式设置
-#show figure: it => {
-  // 居中对齐，无首行缩进
+// 图片对齐方式，默认居中，可由图片属性 {align=left} 临时修改
+#let figure-align = state("figure-align", center)
+
+// 图片、表格样式设置
+#show figure: it => context {
+  // 居中对齐（图片可另行指定），无首行缩进
   set par(first-line-indent: 0pt)
-  align(center, block({
+  let fig-align = if it.kind == table { center } else { figure-align.get() }
+  align(fig-align, block({
+    // 表题位于表格上方：3号黑体，格式为"表1 标题"
+    if it.kind == table and it.caption != none {
+      text(
+        font: FONT_HEI,
+        size: zh(3),
+        it.caption,
+      )
+    }
+
     // 图片尺寸由 Lua filter 控制
     it.body
 
     // 图注样式：3号仿宋，格式为"图1 标题"
-    text(
-      font: FONT_FS,
-      size: zh(3),
-      it.caption,
-    )
+    if it.kind != table {
+      text(
+        font: FONT_FS,
+        size: zh(3),
+        it.caption,
+      )
+    }
   }))
 }
 
+// 图片自适应：宽、高均不超过 13.4cm，保持原始比例
+#let fit-image(path, max-size: 13.4cm) = context {
+  let img = image(path)
+  let img-size = measure(img)
+  let x = img-size.width
+  let y = img-size.height
+
+  let new-x = x
+  let new-y = y
+
+  if x > max-size {
+    let scale = max-size / x
+    new-x = max-size
+    new-y = y * scale
+  }
+
+  if new-y > max-size {
+    let scale = max-size / new-y
+    new-x = new-x * scale
+    new-y = max-size
+  }
+
+  image(path, width: new-x, height: new-y)
+}
+
+// 多图排版：同一段落中的多张图片按行等高排列，行高不低于 6cm；
+// images 为 (path: 路径, caption: 文件名, alt: 替代文字) 的数组。
+// subfigure 为真时作为一个整体图，各图以 (a)(b) 编号，caption 为总图题
+#let multi-image(images, subfigure: false, caption: "") = context {
+  let gap = 0.3cm
+  let max-width = 13.4cm
+  let min-height = 6cm
+
+  let sizes = images.map(item => {
+    let s = measure(image(item.path))
+    (width: s.width, height: s.height, path: item.path, caption: item.caption, alt: item.alt, ratio: s.width / s.height)
+  })
+
+  let calc-row-height(imgs, total-width) = {
+    let ratio-sum = imgs.map(i => i.ratio).sum()
+    total-width / ratio-sum
+  }
+
+  let rows = ()
+
+  if subfigure {
+    rows.push(sizes)
+  } else {
+    let remaining = sizes
+
+    while remaining.len() > 0 {
+      let row = ()
+      let found = false
+
+      for n in range(1, remaining.len() + 1) {
+        let candidate = remaining.slice(0, n)
+        let gaps = (n - 1) * gap
+        let available-width = max-width - gaps
+        let row-h = calc-row-height(candidate, available-width)
+
+        if row-h < min-height and n > 1 {
+          row = remaining.slice(0, n - 1)
+          remaining = remaining.slice(n - 1)
+          found = true
+          break
+        }
+      }
+
+      if not found {
+        row = remaining
+        remaining = ()
+      }
+
+      rows.push(row)
+    }
+  }
+
+  let render-rows(rows) = {
+    for row in rows {
+      let n = row.len()
+      let gaps = (n - 1) * gap
+      let available-width = max-width - gaps
+      let row-height = calc-row-height(row, available-width)
+
+      if row-height > max-width {
+        row-height = max-width
+      }
+
+      align(center, grid(
+        columns: n,
+        gutter: gap,
+        ..row.enumerate().map(item => {
+          let i = item.at(0)
+          let img-data = item.at(1)
+          let w = row-height * img-data.ratio
+
+          if subfigure {
+            let sub-label = numbering("a", i + 1)
+            let sub-text = [ (#sub-label) #img-data.caption ]
+
+            v(0.5em)
+            align(center, block({
+              image(img-data.path, width: w, height: row-height)
+              align(center, text(font: FONT_FS, size: zh(3))[#sub-text])
+            }))
+          } else {
+            figure(
+              image(img-data.path, width: w, height: row-height),
+              caption: [ #img-data.caption ],
+            )
+          }
+        })
+      ))
+      if subfigure { v(0.5em) } else { v(0.3em) }
+    }
+  }
+
+  if subfigure {
+    figure(
+      context { render-rows(rows) },
+      caption: [ #caption ],
+    )
+  } else {
+    render-rows(rows)
+  }
+}
+
+// 表格样式：4号仿宋正文，表头黑体，单元格内不缩进
+#show table: set text(font: FONT_FS, size: zh(4))
+#show table: set par(first-line-indent: 0pt, justify: false, leading: 0.65em)
+#show table.cell.where(y: 0): set text(font: FONT_HEI)
+
 // 自定义标题函数
 #let custom-heading(level, body, numbering: auto) = {
   if level == 1 {
@@ -197,3 +374,203 @@
 #let name(name) = align(center, pad(bottom: 0.8em)[
   #text(font: FONT_KAI, size: zh(3))[#name]
 ])
+
+// 版头颜色：发文机关标志与分隔线
+#let COLOR_RED = rgb(230, 0, 18)
+
+// 空 n 行：按正文 3 号字加行距计算
+#let blank-lines(n) = v(n * (zh(3) + 15.6pt))
+
+// 发文机关标志：小标宋红色，超出版心宽度时横向压缩
+#let issuer-mark(body) = context {
+  let mark = text(font: FONT_XBS, size: zh(0), fill: COLOR_RED, body)
+  let width = measure(mark).width
+  let max-width = 156mm // 版心宽度
+  if width > max-width {
+    scale(x: max-width / width * 100%, reflow: true, mark)
+  } else {
+    mark
+  }
+}
+
+// 版头：份号、密级和保密期限、紧急程度、发文机关标志、发文字号、签发人、红色分隔线
+#let doc-header(
+  copy-number: none,
+  classification: none,
+  urgency: none,
+  issuer: (),
+  doc-number: none,
+  signers: (),
+) = {
+  set par(first-line-indent: 0pt, justify: false)
+
+  // 份号、密级和保密期限、紧急程度：版心左上角顶格，自上而下分行排列
+  let marks = ()
+  if copy-number != none { marks.push(text(font: FONT_FS, copy-number)) }
+  if classification != none { marks.push(text(font: FONT_HEI, classification)) }
+  if urgency != none { marks.push(text(font: FONT_HEI, urgency)) }
+  let marks-block = marks.join(linebreak())
+
+  if issuer.len() == 0 {
+    // 无发文机关标志时仅保留左上角标注
+    marks-block
+    if marks.len() > 0 { blank-lines(1) }
+  } else {
+    // 发文机关标志上边缘至版心上边缘为 35mm
+    block(width: 100%, height: 35mm, spacing: 0pt, marks-block)
+    align(center, stack(spacing: 8pt, ..issuer.map(issuer-mark)))
+  }
+
+  // 发文字号：标志下空二行；有签发人时居左空一字，签发人居右空一字
+  if doc-number != none or signers.len() > 0 {
+    blank-lines(2)
+    if signers.len() == 0 {
+      align(center, doc-number)
+    } else {
+      let signer-cells = ()
+      for (i, signer) in signers.enumerate() {
+        signer-cells.push(if i == 0 [签发人：] else [])
+        signer-cells.push(text(font: FONT_KAI, signer))
+      }
+      grid(
+        columns: (1fr, auto),
+        align: (left + bottom, left + bottom),
+        pad(left: 1em)[#doc-number],
+        pad(right: 1em, grid(columns: 2, row-gutter: 15.6pt, ..signer-cells)),
+      )
+    }
+  }
+
+  // 红色分隔线：发文字号之下 4mm，与版心等宽；标题位于分隔线下空二行
+  if issuer.len() > 0 {
+    v(4mm)
+    line(length: 100%, stroke: 1pt + COLOR_RED)
+    blank-lines(2)
+  }
+}
+
+// 版记：抄送、印发机关和印发日期，置于偶数页版心底部
+#let doc-footer(cc: (), printed-by: none, printed-date: none) = {
+  [#metadata(none) <doc-body-end>]
+
+  let footer-block = {
+    set text(font: FONT_FS, size: zh(4))
+    set par(first-line-indent: 0pt, justify: true, leading: 0.65em)
+    let thick = 0.35mm
+    let thin = 0.25mm
+
+    // 首条分隔线（粗线）
+    line(length: 100%, stroke: thick)
+    if cc.len() > 0 {
+      // 抄送：左右各空一字，回行时与冒号后的首字对齐，末尾标句号
+      block(above: 0.5em, below: 0.5em, pad(x: 1em, grid(
+        columns: (auto, 1fr),
+        [抄送：], cc.join("，") + "。",
+      )))
+      if printed-by != none or printed-date != none {
+        line(length: 100%, stroke: thin)
+      }
+    }
+    if printed-by != none or printed-date != none {
+      // 印发机关左空一字，印发日期右空一字
+      block(above: 0.5em, below: 0.5em, pad(x: 1em, grid(
+        columns: (1fr, auto),
+        [#printed-by],
+        [#if printed-date != none [#printed-date;印发]],
+      )))
+    }
+    // 末条分隔线（粗线）与版心下边缘重合
+    line(length: 100%, stroke: thick)
+  }
+
+  context {
+    let version-bottom = 297mm - 35mm // 版心下边缘
+    let remaining = version-bottom - here().position().y
+    let needed = measure(width: 156mm, block(footer-block)).height
+    // 版记须位于偶数页；当前页为奇数页或剩余空间不足时转到下一偶数页（必要时插入空白页）
+    if calc.odd(here().page()) or remaining < needed + 1em {
+      pagebreak(to: "even")
+    }
+    place(bottom, block(width: 100%, footer-block))
+  }
+}
+
+// 主送机关：标题下空一行，居左顶格，回行时仍顶格，最后一个机关名称后标全角冒号
+#let recipients(items) = block(par(first-line-indent: 0pt, items.join("、") + "："))
+
+// 附件说明：正文下空一行左空二字，多个附件用阿拉伯数字标注顺序号，回行时与附件名称首字对齐
+#let attachment-note(items) = {
+  set par(first-line-indent: 0pt)
+  blank-lines(1)
+  pad(left: 2em, if items.len() == 1 {
+    grid(columns: (auto, 1fr), [附件：], items.first())
+  } else {
+    let cells = ()
+    for (i, item) in items.enumerate(start: 1) {
+      cells.push(if i == 1 [附件：] else [])
+      cells.push([#i.])
+      cells.push(item)
+    }
+    grid(columns: (auto, auto, 1fr), row-gutter: 15.6pt, ..cells)
+  })
+}
+
+// 附件：另面编排，"附件"及顺序号用 3 号黑体顶格编排在版心左上角第一行，
+// 附件标题居中编排在版心第三行，附件内的标题序号重新编排
+#let attachment-page(label, title) = {
+  pagebreak(weak: true)
+  h2-counter.update(0)
+  h3-counter.update(0)
+  h4-counter.update(0)
+  h5-counter.update(0)
+  block(par(first-line-indent: 0pt, text(font: FONT_HEI, label)))
+  blank-lines(1)
+  custom-heading(1, title)
+}
+
+// 发文机关署名和成文日期（参照 GB/T 9704 7.3.5）
+// signers 为 (name: 署名, seal: 是否预留印章位置) 的数组
+#let signature-block(signers, date-text) = {
+  set par(first-line-indent: 0pt, justify: false)
+
+  if signers.any(s => s.seal) {
+    // 加盖印章：印章居中下压署名，署名上方预留约三行印章位置；
+    // 每排最多三个，最后一排靠右，使最后一个印章同时下压成文日期
+    let seal-space = 3 * (zh(3) + 15.6pt)
+    let cols = calc.min(signers.len(), 3)
+    let rows = calc.ceil(signers.len() / cols)
+    let last-start = (rows - 1) * cols
+    let signer-cell(s) = block(width: 100%, {
+      if s.seal { v(seal-space) }
+      align(center, s.name)
+    })
+
+    let cells = signers.slice(0, last-start).map(signer-cell)
+    cells += range(rows * cols - signers.len()).map(i => [])
+    cells += signers.slice(last-start).map(signer-cell)
+    cells += range(cols - 1).map(i => [])
+    cells.push(align(center, date-text))
+
+    if cols == 1 {
+      // 单一机关：成文日期右空四字
+      align(right, pad(right: 4em, grid(columns: 1, row-gutter: 15.6pt, ..cells)))
+    } else {
+      grid(
+        columns: (1fr,) * cols,
+        column-gutter: 1em,
+        row-gutter: 15.6pt,
+        align: center + bottom,
+        ..cells,
+      )
+    }
+  } else {
+    // 不加盖印章：署名右空二字，主办机关在前依次向下编排；成文日期首字比署名首字右移二字
+    align(right, pad(right: 2em, block({
+      set align(left)
+      signers.map(s => s.name).join(linebreak())
+      linebreak()
+      h(2em)
+      date-text
+    })))
+  }
+}
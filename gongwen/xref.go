@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+	"github.com/yuin/goldmark/ast"
+)
+
+// ---------- Attributes and cross-references ----------
+
+// attributes is a Pandoc-style attribute block: {#id .class key=value}.
+type attributes struct {
+	ID      string
+	Classes []string
+	Values  map[string]string
+}
+
+// hasClass reports whether the attribute block lists the given class.
+func (a attributes) hasClass(name string) bool {
+	for _, c := range a.Classes {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether no attribute was given at all.
+func (a attributes) isEmpty() bool {
+	return a.ID == "" && len(a.Classes) == 0 && len(a.Values) == 0
+}
+
+// dimension returns the named attribute of a if it is a non-negative Typst
+// length or ratio; fractions such as 1fr have no meaning for an image. Other
+// values are reported at the given input line and ignored, giving nil.
+func (c *converter) dimension(a attributes, key string, line int) typst.Expr {
+	v, ok := a.Values[key]
+	if !ok {
+		return nil
+	}
+	e, ok := typst.ParseLength(v)
+	if ok {
+		code := typst.Code(e)
+		ok = !strings.HasPrefix(code, "-") && !strings.HasSuffix(code, "fr")
+	}
+	if !ok {
+		c.warnf(line, "invalid-dimension", "%s=%s 不是有效的长度（如 60%%、8cm），已忽略", key, v)
+		return nil
+	}
+	return e
+}
+
+// parseAttributes parses "{#id .class key=value key="quoted value"}".
+// It fails if s is not a single brace-delimited block or an item is malformed.
+func parseAttributes(s string) (attributes, bool) {
+	var a attributes
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return a, false
+	}
+	items, ok := splitAttributeItems(s[1 : len(s)-1])
+	if !ok {
+		return a, false
+	}
+	for _, item := range items {
+		switch {
+		case strings.HasPrefix(item, "#"):
+			if !typst.IsLabelName(item[1:]) {
+				return a, false
+			}
+			a.ID = item[1:]
+		case strings.HasPrefix(item, "."):
+			a.Classes = append(a.Classes, item[1:])
+		case item == "-":
+			a.Classes = append(a.Classes, "unnumbered") // Pandoc shorthand
+		default:
+			key, value, found := strings.Cut(item, "=")
+			if !found || key == "" {
+				return a, false
+			}
+			if a.Values == nil {
+				a.Values = map[string]string{}
+			}
+			a.Values[key] = strings.Trim(value, `"`)
+		}
+	}
+	return a, true
+}
+
+// splitAttributeItems splits on whitespace, keeping double-quoted values together.
+func splitAttributeItems(s string) ([]string, bool) {
+	var items []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuote:
+			if cur.Len() > 0 {
+				items = append(items, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		items = append(items, cur.String())
+	}
+	return items, !inQuote
+}
+
+// trailingAttrRe matches an attribute block at the end of a line of text.
+var trailingAttrRe = regexp.MustCompile(`\s*(\{[^{}]*\})\s*$`)
+
+// splitTrailingID splits "标题 {#sec:goal}" into the text and the label ID.
+// Blocks without an ID (such as {.noindent}) are left in place.
+func splitTrailingID(text string) (string, string, string) {
+	m := trailingAttrRe.FindStringSubmatchIndex(text)
+	if m == nil {
+		return text, "", ""
+	}
+	block := text[m[2]:m[3]]
+	attrs, ok := parseAttributes(block)
+	if !ok || attrs.ID == "" {
+		return text, "", ""
+	}
+	return text[:m[0]], attrs.ID, block
+}
+
+// refRe matches a cross-reference such as {@fig:plan}, {@tab:budget} or {@sec:goal}.
+var refRe = regexp.MustCompile(`\{@([^{}\s]+)\}`)
+
+// renderText escapes a run of plain text, which starts at byte offset start in
+// the source, and resolves the cross-references in it.
+func (c *converter) renderText(raw string, start int) string {
+	var buf strings.Builder
+	last := 0
+	for _, m := range refRe.FindAllStringSubmatchIndex(raw, -1) {
+		buf.WriteString(typst.EscapeContent(convertPunctuation(raw[last:m[0]])))
+		line := c.line(start) + strings.Count(raw[:m[0]], "\n")
+		buf.WriteString(c.renderRef(raw[m[2]:m[3]], raw[m[0]:m[1]], line))
+		last = m[1]
+	}
+	buf.WriteString(typst.EscapeContent(convertPunctuation(raw[last:])))
+	return buf.String()
+}
+
+// renderRef renders a reference to id, written on the given input line, as a
+// link showing e.g. "图1" or "（一）工作目标". Unknown IDs are kept as literal
+// text and reported.
+func (c *converter) renderRef(id, literal string, line int) string {
+	c.referenced[id] = true
+	target, ok := c.refTargets[id]
+	if !ok || !typst.IsLabelName(id) {
+		c.warnf(line, "unresolved-reference", "找不到 %s 引用的图、表或标题，按原文输出", literal)
+		return typst.EscapeContent(literal)
+	}
+	return string(typst.Inline(typst.Call("link", typst.Pos(typst.LabelValue(id))).Body(typst.Text(target))))
+}
+
+// addTarget records the reference text for id and returns the label name to
+// attach to the element, or "" if id is empty or already taken.
+func (c *converter) addTarget(id, text string) string {
+	if id == "" || !typst.IsLabelName(id) || c.labels[id] {
+		return ""
+	}
+	c.labels[id] = true
+	c.refTargets[id] = text
+	return id
+}
+
+// headingNumber advances the heading counters and returns the number as printed
+// by custom-heading in template_head.typ: "一、", "（一）", "1.", "（1）".
+func (c *converter) headingNumber(level int) string {
+	if level < 2 || level > 5 {
+		return ""
+	}
+	c.headingCounters[level]++
+	for l := level + 1; l < len(c.headingCounters); l++ {
+		c.headingCounters[l] = 0
+	}
+	n := c.headingCounters[level]
+	switch level {
+	case 2:
+		return chineseNumber(n) + "、"
+	case 3:
+		return "（" + chineseNumber(n) + "）"
+	case 4:
+		return fmt.Sprintf("%d.", n)
+	default:
+		return fmt.Sprintf("（%d）", n)
+	}
+}
+
+// resetHeadingNumbers restarts heading numbering, as attachment-page does.
+func (c *converter) resetHeadingNumbers() {
+	c.headingCounters = [6]int{}
+}
+
+// headingLabel registers h as a reference target and returns its label name.
+// An explicit {#id} always gets a label; goldmark's automatic ID only when it is
+// actually referenced somewhere, to keep the output free of unused labels.
+func (c *converter) headingLabel(h *ast.Heading, explicitID, number, title string) string {
+	id := explicitID
+	if id == "" {
+		if auto, ok := h.AttributeString("id"); ok {
+			if b, ok := auto.([]byte); ok && c.referenced[string(b)] {
+				id = string(b)
+			}
+		}
+	}
+	return c.addTarget(id, number+title)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
 )
 
 // Run implements the standard template CLI protocol:
@@ -13,10 +15,22 @@ import (
 //   - --example  → print exampleMD
 //   - --version  → extract and print version from manifestJSON
 //   - otherwise  → read stdin, call convert, print result
-func Run(manifestJSON, exampleMD string, convert func(string) string) {
+//
+// Diagnostics reported by convert are printed to stderr, one per line, as text
+// or, with --json-diagnostics, as JSON objects. If any is an error the exit
+// status is 1; the result is still printed. Errors therefore mean the document
+// must be corrected before it is accepted, not that the output is unusable:
+// a lesson plan whose class hours disagree with 总课时 still renders, but is
+// reported as an error. Problems the template repairs itself are warnings.
+//
+// With --validate the result is checked with typst.Validate first; syntax errors
+// are reported as "typst-syntax" errors instead of printing the result.
+func Run(manifestJSON, exampleMD string, convert Converter) {
 	manifestFlag := flag.Bool("manifest", false, "output manifest JSON")
 	exampleFlag := flag.Bool("example", false, "output example markdown")
 	versionFlag := flag.Bool("version", false, "output version")
+	validateFlag := flag.Bool("validate", false, "check the Typst output for syntax errors and injected code")
+	jsonFlag := flag.Bool("json-diagnostics", false, "print diagnostics as JSON lines")
 	flag.Parse()
 
 	if *versionFlag {
@@ -50,5 +64,19 @@ func Run(manifestJSON, exampleMD string, convert func(string) string) {
 		os.Exit(1)
 	}
 
-	fmt.Print(convert(string(input)))
+	output, diags := convert(string(input))
+	valid := true
+	if *validateFlag {
+		for _, e := range typst.Validate(output) {
+			diags = append(diags, Errorf(0, 0, "typst-syntax", "generated Typst %v", e))
+			valid = false
+		}
+	}
+	if valid {
+		fmt.Print(output)
+	}
+	writeDiagnostics(os.Stderr, diags, *jsonFlag)
+	if hasErrors(diags) {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Severity classifies a Diagnostic. Any error makes the CLI exit with status 1.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found while converting. Line and Column are 1-based
+// positions in the Markdown input; 0 means the position is unknown.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// Errorf returns an error diagnostic at line:column.
+func Errorf(line, column int, code, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{SeverityError, line, column, code, fmt.Sprintf(format, args...)}
+}
+
+// Warningf returns a warning diagnostic at line:column.
+func Warningf(line, column int, code, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{SeverityWarning, line, column, code, fmt.Sprintf(format, args...)}
+}
+
+// String formats d as "line:column: severity: message [code]".
+func (d Diagnostic) String() string {
+	pos := ""
+	switch {
+	case d.Line > 0 && d.Column > 0:
+		pos = fmt.Sprintf("%d:%d: ", d.Line, d.Column)
+	case d.Line > 0:
+		pos = fmt.Sprintf("%d: ", d.Line)
+	}
+	return fmt.Sprintf("%s%s: %s [%s]", pos, d.Severity, d.Message, d.Code)
+}
+
+// Converter turns Markdown input into Typst output, reporting the problems it
+// finds in the input as diagnostics.
+type Converter func(input string) (string, []Diagnostic)
+
+// writeDiagnostics prints diags to w, one per line, as text or as JSON objects.
+func writeDiagnostics(w io.Writer, diags []Diagnostic, asJSON bool) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, d := range diags {
+		if asJSON {
+			enc.Encode(d)
+		} else {
+			fmt.Fprintln(w, d)
+		}
+	}
+}
+
+// hasErrors reports whether any diagnostic is an error.
+func hasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
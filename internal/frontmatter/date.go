@@ -0,0 +1,49 @@
+package frontmatter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datePatterns are the accepted spellings of a date, each capturing year, month, day.
+var datePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})$`),          // 2025-03-15, 2025/3/15, 2025.3.15
+	regexp.MustCompile(`^(\d{4})\s*年\s*(\d{1,2})\s*月\s*(\d{1,2})\s*日?$`), // 2025年3月15日
+	// YAML timestamps, whose time of day is dropped: 2025-03-15T10:00:00Z, 2025-03-15 10:30:00.
+	regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})(?:[Tt]|\s+)\d{1,2}:\d{2}:\d{2}(?:\.\d*)?\s*(?:Z|[-+]\d{1,2}(?::?\d{2})?)?$`),
+}
+
+// ParseDate normalises the accepted date spellings, including "today"/"今天"
+// and YAML timestamps, and rejects dates that do not exist (e.g. 2025-02-30).
+// It is the check behind the "YYYY-MM-DD" schema format.
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "today", "今天", "今日":
+		y, m, d := time.Now().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true
+	}
+	for _, re := range datePatterns {
+		m := re.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// FormatDate writes the date of t in Arabic numerals as "2025年3月15日".
+func FormatDate(t time.Time) string {
+	return fmt.Sprintf("%d年%d月%d日", t.Year(), int(t.Month()), t.Day())
+}
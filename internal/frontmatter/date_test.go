@@ -0,0 +1,56 @@
+package frontmatter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" if the date is rejected
+	}{
+		{"2025-03-15", "2025-03-15"},
+		{"2025/3/5", "2025-03-05"},
+		{"2025.3.15", "2025-03-15"},
+		{" 2025年3月15日 ", "2025-03-15"},
+		{"2025 年 3 月 15", "2025-03-15"},
+		{"2025-03-15T10:00:00Z", "2025-03-15"},
+		{"2025-03-15 10:30:00", "2025-03-15"},
+		{"2025-03-15t23:59:59.5+08:00", "2025-03-15"},
+		{"2025-02-30", ""},
+		{"2025-13-01", ""},
+		{"25-03-15", ""},
+		{"2025-03-15T10:00", ""},
+		{"三月十五日", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.in)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("ParseDate(%q) = %v, want rejected", tt.in, got)
+			}
+			continue
+		}
+		if !ok || got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseDate(%q) = %v, %v, want %s", tt.in, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseDateToday(t *testing.T) {
+	y, m, d := time.Now().Date()
+	for _, s := range []string{"today", "Today", "今天", "今日"} {
+		got, ok := ParseDate(s)
+		if !ok || got.Year() != y || got.Month() != m || got.Day() != d {
+			t.Errorf("ParseDate(%q) = %v, %v, want today", s, got, ok)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	if got := FormatDate(time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)); got != "2025年3月5日" {
+		t.Errorf("FormatDate = %q, want %q", got, "2025年3月5日")
+	}
+}
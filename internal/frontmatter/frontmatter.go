@@ -0,0 +1,295 @@
+// Package frontmatter parses the YAML front matter of template input and
+// validates it against the frontmatterSchema declared in the template manifest.
+package frontmatter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is an input split into validated front matter and Markdown body.
+type Document struct {
+	// Values holds the front-matter values, with schema defaults filled in for
+	// missing keys and for values that failed validation. Strings keep the text
+	// as written, so unquoted dates and numbers like 000123 are not reformatted.
+	Values map[string]interface{}
+	// Lines maps each top-level key written in the front matter to its input line.
+	Lines map[string]int
+
+	Body     string // Markdown after the front matter
+	BodyLine int    // 1-based input line on which Body starts
+}
+
+// String returns the value of key as trimmed text; other scalars are formatted.
+func (d Document) String(key string) string {
+	switch v := d.Values[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
+
+// Strings returns the non-empty items of key, which may be one string or a list.
+func (d Document) Strings(key string) []string {
+	var items []string
+	switch v := d.Values[key].(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v {
+			if s := strings.TrimSpace(fmt.Sprint(item)); s != "" {
+				items = append(items, s)
+			}
+		}
+	default:
+		if s := strings.TrimSpace(fmt.Sprint(v)); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
+
+// Bool returns the value of key if it is a boolean, otherwise false.
+func (d Document) Bool(key string) bool {
+	b, _ := d.Values[key].(bool)
+	return b
+}
+
+// Parse splits the "---" delimited YAML front matter off input, validates it
+// against schema and fills in the schema's defaults. Problems are reported as
+// diagnostics with input line numbers; values that fail validation are dropped
+// in favour of the default, so the document can still be rendered.
+func Parse(input string, schema Schema) (Document, []cli.Diagnostic) {
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	doc := Document{Values: map[string]interface{}{}, Lines: map[string]int{}, Body: input, BodyLine: 1}
+	p := &parser{schema: schema}
+
+	lines := strings.SplitAfter(input, "\n")
+	if strings.TrimRight(lines[0], " \t\n") == "---" {
+		end := -1
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimRight(lines[i], " \t\n") == "---" {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			p.diags = append(p.diags, cli.Warningf(1, 1, "front-matter-unclosed", "front matter 没有以 --- 结束，已按正文处理"))
+		} else {
+			doc.Body = strings.Join(lines[end+1:], "")
+			doc.BodyLine = end + 2
+			p.parse(strings.Join(lines[1:end], ""), doc)
+		}
+	}
+
+	p.complete(doc.Values, schema, "", 1)
+	return doc, p.diags
+}
+
+// parser validates one front-matter block.
+type parser struct {
+	schema Schema
+	diags  []cli.Diagnostic
+}
+
+// reservedKeys are read by Presto itself and are valid in every template.
+var reservedKeys = map[string]bool{
+	"template": true, // selects the template that converts the document
+}
+
+// blockOffset converts yaml.v3 line numbers, counted from the start of the
+// block, to input lines: the block starts after the opening "---".
+const blockOffset = 1
+
+// yamlErrorRe extracts the line and message from a yaml.v3 error message.
+var yamlErrorRe = regexp.MustCompile(`line (\d+): (.*)`)
+
+// parse decodes block and stores its validated top-level values, and the lines
+// of their keys, in doc.
+func (p *parser) parse(block string, doc Document) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(block), &root); err != nil {
+		for _, msg := range strings.Split(err.Error(), "\n") {
+			if m := yamlErrorRe.FindStringSubmatch(msg); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				p.diags = append(p.diags, cli.Errorf(line+blockOffset, 0, "yaml-syntax", "front matter 语法错误：%s", m[2]))
+			}
+		}
+		if len(p.diags) == 0 {
+			p.diags = append(p.diags, cli.Errorf(1, 0, "yaml-syntax", "front matter 语法错误：%s", strings.TrimPrefix(err.Error(), "yaml: ")))
+		}
+		return
+	}
+	if len(root.Content) == 0 {
+		return // empty front matter
+	}
+	n := root.Content[0]
+	if n.Kind != yaml.MappingNode {
+		p.errorf(n, "front-matter-type", "front matter 应由 key: value 形式的键值对组成")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		field, known := p.schema[k.Value]
+		if !known && len(p.schema) > 0 && !reservedKeys[k.Value] {
+			p.warnf(k, "front-matter-unknown-key", "未知的 front matter 字段 %q", k.Value)
+		}
+		doc.Lines[k.Value] = k.Line + blockOffset
+		if val, ok := p.value(v, field, k.Value); ok {
+			doc.Values[k.Value] = val
+		}
+	}
+}
+
+// value validates n against f and returns the value to keep, if any. path names
+// the value in messages, e.g. "author[2].seal".
+func (p *parser) value(n *yaml.Node, f *Field, path string) (interface{}, bool) {
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	kind := nodeKind(n)
+	if kind == "null" {
+		return nil, false
+	}
+	if f == nil {
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, false
+		}
+		return v, true
+	}
+
+	if !f.Type.allows(kind) {
+		switch {
+		case n.Kind == yaml.ScalarNode && f.Type.allows("boolean") && boolWords[strings.ToLower(n.Value)] != "":
+			p.warnf(n, "front-matter-type", "%s：应为布尔值，而不是 %q；请写 true 或 false", path, n.Value)
+			return boolWords[strings.ToLower(n.Value)] == "true", true
+		case n.Kind == yaml.ScalarNode && f.Type.allows("string"):
+			p.warnf(n, "front-matter-type", "%s：应为文字，而不是%s；请加引号", path, typeName(kind))
+			kind = "string"
+		default:
+			p.warnf(n, "front-matter-type", "%s：应为%s，而不是%s，已忽略", path, f.Type, typeName(kind))
+			return nil, false
+		}
+	}
+
+	switch kind {
+	case "string":
+		s := n.Value
+		if len(f.Enum) > 0 && !contains(f.Enum, s) {
+			p.warnf(n, "front-matter-enum", "%s：%q 不是 %s 之一，已忽略", path, s, strings.Join(f.Enum, "、"))
+			return nil, false
+		}
+		if f.Format == "YYYY-MM-DD" {
+			if _, ok := ParseDate(s); !ok {
+				p.warnf(n, "front-matter-format", "%s：%q 不是有效的日期，应写作 YYYY-MM-DD", path, s)
+			}
+		}
+		return s, true
+	case "array":
+		items := []interface{}{}
+		for i, child := range n.Content {
+			if v, ok := p.value(child, f.Items, fmt.Sprintf("%s[%d]", path, i+1)); ok {
+				items = append(items, v)
+			}
+		}
+		return items, true
+	case "object":
+		obj := map[string]interface{}{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			prop, known := f.Properties[k.Value]
+			if !known && len(f.Properties) > 0 {
+				p.warnf(k, "front-matter-unknown-key", "%s：未知的字段 %q", path, k.Value)
+			}
+			if val, ok := p.value(v, prop, path+"."+k.Value); ok {
+				obj[k.Value] = val
+			}
+		}
+		p.complete(obj, f.Properties, path+".", n.Line+blockOffset)
+		return obj, true
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, false
+		}
+		return v, true
+	}
+}
+
+// complete reports missing required keys and fills in defaults, in key order.
+func (p *parser) complete(values map[string]interface{}, fields map[string]*Field, prefix string, line int) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		f := fields[key]
+		if f.Required {
+			p.diags = append(p.diags, cli.Errorf(line, 0, "front-matter-required", "缺少必填的 front matter 字段 %q", prefix+key))
+		}
+		if f.Default != nil {
+			values[key] = f.Default
+		}
+	}
+}
+
+// boolWords are the YAML 1.1 spellings of booleans that yaml.v3 reads as strings.
+var boolWords = map[string]string{
+	"yes": "true", "on": "true", "true": "true",
+	"no": "false", "off": "false", "false": "false",
+}
+
+// nodeKind returns the schema type of n: null, string, boolean, integer,
+// number, array or object. Timestamps count as strings.
+func nodeKind(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.MappingNode:
+		return "object"
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return "null"
+		case "!!bool":
+			return "boolean"
+		case "!!int":
+			return "integer"
+		case "!!float":
+			return "number"
+		default:
+			return "string"
+		}
+	}
+	return "null"
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) warnf(n *yaml.Node, code, format string, args ...interface{}) {
+	p.diags = append(p.diags, cli.Warningf(n.Line+blockOffset, n.Column, code, format, args...))
+}
+
+func (p *parser) errorf(n *yaml.Node, code, format string, args ...interface{}) {
+	p.diags = append(p.diags, cli.Errorf(n.Line+blockOffset, n.Column, code, format, args...))
+}
@@ -0,0 +1,138 @@
+package frontmatter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+)
+
+const testManifest = `{
+  "frontmatterSchema": {
+    "title": { "type": "string", "required": true },
+    "date": { "type": "string", "format": "YYYY-MM-DD" },
+    "signature": { "type": "boolean", "default": false },
+    "style": { "type": "string", "enum": ["grid", "three-line"], "default": "grid" },
+    "author": {
+      "type": ["string", "array"],
+      "items": {
+        "type": ["string", "object"],
+        "properties": { "name": { "type": "string" }, "seal": { "type": "boolean" } }
+      }
+    },
+    "count": { "type": "integer" }
+  }
+}`
+
+func TestParse(t *testing.T) {
+	schema := MustParseSchema(testManifest)
+	tests := []struct {
+		name   string
+		input  string
+		values map[string]interface{}
+		diags  []cli.Diagnostic // Message is not compared
+	}{
+		{
+			name:   "defaults",
+			input:  "---\ntitle: 通知\n---\n正文\n",
+			values: map[string]interface{}{"title": "通知", "signature": false, "style": "grid"},
+		},
+		{
+			name:   "no front matter",
+			input:  "正文\n",
+			values: map[string]interface{}{"signature": false, "style": "grid"},
+			diags:  []cli.Diagnostic{{Severity: cli.SeverityError, Line: 1, Code: "front-matter-required"}},
+		},
+		{
+			name:   "number as string",
+			input:  "---\ntitle: 2025\n---\n",
+			values: map[string]interface{}{"title": "2025", "signature": false, "style": "grid"},
+			diags:  []cli.Diagnostic{{Severity: cli.SeverityWarning, Line: 2, Column: 8, Code: "front-matter-type"}},
+		},
+		{
+			name:   "yes as boolean",
+			input:  "---\ntitle: 通知\nsignature: \"yes\"\n---\n",
+			values: map[string]interface{}{"title": "通知", "signature": true, "style": "grid"},
+			diags:  []cli.Diagnostic{{Severity: cli.SeverityWarning, Line: 3, Column: 12, Code: "front-matter-type"}},
+		},
+		{
+			name:   "type mismatch",
+			input:  "---\ntitle: 通知\ncount: [1, 2]\n---\n",
+			values: map[string]interface{}{"title": "通知", "signature": false, "style": "grid"},
+			diags:  []cli.Diagnostic{{Severity: cli.SeverityWarning, Line: 3, Column: 8, Code: "front-matter-type"}},
+		},
+		{
+			name:   "enum",
+			input:  "---\ntitle: 通知\nstyle: dotted\n---\n",
+			values: map[string]interface{}{"title": "通知", "signature": false, "style": "grid"},
+			diags:  []cli.Diagnostic{{Severity: cli.SeverityWarning, Line: 3, Column: 8, Code: "front-matter-enum"}},
+		},
+		{
+			name:  "unknown keys",
+			input: "---\ntitle: 通知\ntemplate: gongwen\ncolour: red\nauthor:\n  - name: 办公室\n    sael: true\n---\n",
+			values: map[string]interface{}{
+				"title": "通知", "template": "gongwen", "colour": "red", "signature": false, "style": "grid",
+				"author": []interface{}{map[string]interface{}{"name": "办公室", "sael": true}},
+			},
+			diags: []cli.Diagnostic{
+				{Severity: cli.SeverityWarning, Line: 4, Column: 1, Code: "front-matter-unknown-key"},
+				{Severity: cli.SeverityWarning, Line: 7, Column: 5, Code: "front-matter-unknown-key"},
+			},
+		},
+		{
+			name:   "YAML timestamp",
+			input:  "---\ntitle: 通知\ndate: 2025-03-15T10:00:00Z\n---\n",
+			values: map[string]interface{}{"title": "通知", "date": "2025-03-15T10:00:00Z", "signature": false, "style": "grid"},
+		},
+		{
+			name:   "invalid date",
+			input:  "---\ntitle: 通知\ndate: 2025-02-30\n---\n",
+			values: map[string]interface{}{"title": "通知", "date": "2025-02-30", "signature": false, "style": "grid"},
+			diags:  []cli.Diagnostic{{Severity: cli.SeverityWarning, Line: 3, Column: 7, Code: "front-matter-format"}},
+		},
+		{
+			name:   "unclosed",
+			input:  "---\ntitle: 通知\n",
+			values: map[string]interface{}{"signature": false, "style": "grid"},
+			diags: []cli.Diagnostic{
+				{Severity: cli.SeverityWarning, Line: 1, Column: 1, Code: "front-matter-unclosed"},
+				{Severity: cli.SeverityError, Line: 1, Code: "front-matter-required"},
+			},
+		},
+		{
+			name:   "YAML syntax",
+			input:  "---\ntitle: 通知\n  bad: [\n---\n",
+			values: map[string]interface{}{"signature": false, "style": "grid"},
+			diags: []cli.Diagnostic{
+				{Severity: cli.SeverityError, Line: 3, Code: "yaml-syntax"},
+				{Severity: cli.SeverityError, Line: 1, Code: "front-matter-required"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, diags := Parse(tt.input, schema)
+			if !reflect.DeepEqual(doc.Values, tt.values) {
+				t.Errorf("Values = %#v, want %#v", doc.Values, tt.values)
+			}
+			for i := range diags {
+				diags[i].Message = ""
+			}
+			if len(diags) != 0 || len(tt.diags) != 0 {
+				if !reflect.DeepEqual(diags, tt.diags) {
+					t.Errorf("diagnostics = %+v, want %+v", diags, tt.diags)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBody(t *testing.T) {
+	doc, _ := Parse("---\ntitle: 通知\n---\n# 正文\n", MustParseSchema(testManifest))
+	if doc.Body != "# 正文\n" || doc.BodyLine != 4 {
+		t.Errorf("Body = %q at line %d, want %q at line 4", doc.Body, doc.BodyLine, "# 正文\n")
+	}
+	if doc.Lines["title"] != 2 {
+		t.Errorf("Lines[title] = %d, want 2", doc.Lines["title"])
+	}
+}
@@ -0,0 +1,112 @@
+package frontmatter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Schema is the frontmatterSchema of a template manifest: one Field per key.
+type Schema map[string]*Field
+
+// Field describes one front-matter key with the subset of JSON Schema that
+// manifests use.
+type Field struct {
+	Type        Types             `json:"type"`
+	Items       *Field            `json:"items,omitempty"`
+	Properties  map[string]*Field `json:"properties,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+	Default     interface{}       `json:"default,omitempty"`
+	Format      string            `json:"format,omitempty"`
+	Required    bool              `json:"required,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+// Types is a JSON Schema "type": one of string, boolean, integer, number,
+// array and object, or a list of them. Empty allows any type.
+type Types []string
+
+// UnmarshalJSON accepts both "string" and ["string", "array"].
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = many
+	return nil
+}
+
+// allows reports whether a value of the given type is valid. Integers are
+// numbers too.
+func (t Types) allows(kind string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, name := range t {
+		if name == kind || name == "number" && kind == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeNames are the names of schema types in messages.
+var typeNames = map[string]string{
+	"null":    "空值",
+	"string":  "文字",
+	"boolean": "布尔值",
+	"integer": "整数",
+	"number":  "数字",
+	"array":   "列表",
+	"object":  "对象",
+}
+
+// typeName returns the name of a schema type for messages.
+func typeName(kind string) string {
+	if name, ok := typeNames[kind]; ok {
+		return name
+	}
+	return kind
+}
+
+// String lists the types for messages: "文字或列表".
+func (t Types) String() string {
+	s := ""
+	for i, name := range t {
+		switch {
+		case i == 0:
+		case i == len(t)-1:
+			s += "或"
+		default:
+			s += "、"
+		}
+		s += typeName(name)
+	}
+	return s
+}
+
+// ParseSchema reads the frontmatterSchema of a template manifest. A manifest
+// without one yields an empty schema, which accepts any front matter.
+func ParseSchema(manifestJSON string) (Schema, error) {
+	var m struct {
+		FrontmatterSchema Schema `json:"frontmatterSchema"`
+	}
+	if err := json.Unmarshal([]byte(manifestJSON), &m); err != nil {
+		return nil, fmt.Errorf("frontmatterSchema: %w", err)
+	}
+	return m.FrontmatterSchema, nil
+}
+
+// MustParseSchema is like ParseSchema but panics if the manifest is invalid.
+// It is meant for the manifest embedded in a template binary.
+func MustParseSchema(manifestJSON string) Schema {
+	s, err := ParseSchema(manifestJSON)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+package jiaoan
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+)
+
+// fieldAliases 是内容标签的其他写法，仅在对应的列存在时生效
+var fieldAliases = map[string]string{
+	"教学方法": "教学方法与手段",
+	"教学手段": "教学方法与手段",
+}
+
+// fieldLabelRe 匹配段落开头的标签，如 "学习内容：" 或 "学生活动:"
+var fieldLabelRe = regexp.MustCompile(`^([^\s：:]{2,8})\s*[：:]\s*`)
+
+// fieldName 返回标签在 fields 中对应的列名；不是内容标签时返回空字符串
+func fieldName(label string, fields []string) string {
+	label = strings.TrimSpace(label)
+	if alias, ok := fieldAliases[label]; ok {
+		label = alias
+	}
+	for _, f := range fields {
+		if f == label {
+			return f
+		}
+	}
+	return ""
+}
+
+// splitFieldLabel 拆出段落开头的内容标签，返回列名和其余文字
+func splitFieldLabel(text string, fields []string) (string, string) {
+	m := fieldLabelRe.FindStringSubmatch(text)
+	if m == nil {
+		return "", text
+	}
+	name := fieldName(m[1], fields)
+	if name == "" {
+		return "", text
+	}
+	return name, text[len(m[0]):]
+}
+
+// resolveFields 将五级标题下的内容归集到各列，结果存入 h5.Fields。带标签的
+// 段落可按任意顺序书写，其后不带标签的段落属于同一列；全部不带标签时按
+// 段落顺序依次填入 fields，中间没有空行的块（如紧跟段落的列表）算作一段。
+// 缺少的列和多余的段落会被报告
+func resolveFields(h5 *H5Block, fields []string) []cli.Diagnostic {
+	var diags []cli.Diagnostic
+	h5.Fields = map[string]string{}
+	add := func(field, text string) {
+		if h5.Fields[field] == "" {
+			h5.Fields[field] = text
+		} else if text != "" {
+			h5.Fields[field] += "\n" + text
+		}
+	}
+
+	labelled := false
+	for _, p := range h5.Content {
+		if p.Label != "" {
+			labelled = true
+			break
+		}
+	}
+
+	seen := map[string]bool{}
+	if labelled {
+		current := ""
+		for _, p := range h5.Content {
+			if p.Label != "" {
+				current = p.Label
+			}
+			if current == "" {
+				diags = append(diags, cli.Warningf(p.Line, 1, "content-surplus", "内容没有标签且位于第一个标签之前，已忽略"))
+				continue
+			}
+			seen[current] = true
+			add(current, p.Text)
+		}
+	} else {
+		var paras []Paragraph
+		for _, p := range h5.Content {
+			if p.Joined && len(paras) > 0 {
+				paras[len(paras)-1].Text += "\n" + p.Text
+				continue
+			}
+			paras = append(paras, p)
+		}
+		for i, p := range paras {
+			if i >= len(fields) {
+				diags = append(diags, cli.Warningf(p.Line, 1, "content-surplus", "##### %s 下只有 %d 列内容，第 %d 段已忽略；可用 \"%s：\" 等标签指明所属列", h5.Title, len(fields), i+1, fields[0]))
+				continue
+			}
+			seen[fields[i]] = true
+			add(fields[i], p.Text)
+		}
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if !seen[f] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		diags = append(diags, cli.Warningf(h5.Line, 1, "content-missing", "##### %s 下缺少 %s", h5.Title, strings.Join(missing, "、")))
+	}
+	return diags
+}
@@ -0,0 +1,98 @@
+package jiaoan
+
+import (
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+)
+
+// LessonInfo 存储 front matter 中的课程信息，渲染为活动表格上方的教案表头
+type LessonInfo struct {
+	Course     string   // 课程名称
+	Class      string   // 授课班级
+	Teacher    string   // 授课教师
+	Date       string   // 授课日期
+	Location   string   // 授课地点
+	TotalHours string   // 总课时
+	Objectives []string // 教学目标
+	KeyPoints  []string // 教学重难点
+	Resources  []string // 教学资源
+}
+
+// parseLessonInfo 从已校验的 front matter 中读取课程信息
+func parseLessonInfo(doc frontmatter.Document) LessonInfo {
+	return LessonInfo{
+		Course:     doc.String("课程名称"),
+		Class:      doc.String("授课班级"),
+		Teacher:    doc.String("授课教师"),
+		Date:       doc.String("授课日期"),
+		Location:   doc.String("授课地点"),
+		TotalHours: doc.String("总课时"),
+		Objectives: doc.Strings("教学目标"),
+		KeyPoints:  doc.Strings("教学重难点"),
+		Resources:  doc.Strings("教学资源"),
+	}
+}
+
+// isEmpty 判断是否未填写任何课程信息；此时不输出表头
+func (l LessonInfo) isEmpty() bool {
+	return l.Course == "" && l.Class == "" && l.Teacher == "" && l.Date == "" &&
+		l.Location == "" && l.TotalHours == "" &&
+		len(l.Objectives) == 0 && len(l.KeyPoints) == 0 && len(l.Resources) == 0
+}
+
+// displayDate 将可识别的授课日期写作"2025年3月15日"，其余原样输出
+func displayDate(date string) string {
+	t, ok := frontmatter.ParseDate(date)
+	if !ok {
+		return date
+	}
+	return frontmatter.FormatDate(t)
+}
+
+// listContent 将多项内容渲染为带序号的多行，单项原样输出
+func listContent(items []string) typst.Markup {
+	lines := make([]cellLine, len(items))
+	for i, item := range items {
+		lines[i] = cellLine{Markup: typst.Text(item)}
+	}
+	if len(lines) == 1 {
+		return lines[0].Markup
+	}
+	content, _ := formatNumberedContent(lines, 1)
+	return content
+}
+
+// renderLessonHeader 生成教案表头：课程名称、授课班级、授课教师、授课日期、
+// 授课地点、总课时各占一格，教学目标、教学重难点、教学资源各占一整行
+func renderLessonHeader(info LessonInfo) string {
+	if info.isEmpty() {
+		return ""
+	}
+	label := func(text string) typst.Arg {
+		return typst.Pos(typst.Content("*" + typst.Text(text) + "*"))
+	}
+	value := func(text string) typst.Arg {
+		return typst.Pos(typst.Content(typst.Text(text)))
+	}
+	wide := func(items []string) typst.Arg {
+		return typst.Pos(typst.TableCell(listContent(items),
+			typst.Named("colspan", typst.Int(5)),
+			typst.Named("align", typst.Op("+", typst.Ident("left"), typst.Ident("horizon"))),
+		))
+	}
+
+	tbl := typst.Call("table",
+		typst.Named("columns", typst.Array(
+			typst.Length("2.3cm"), typst.Length("1fr"), typst.Length("2.3cm"), typst.Length("1fr"), typst.Length("2.3cm"), typst.Length("1fr"),
+		)),
+		typst.Named("stroke", typst.Length("0.5pt")),
+		typst.Named("inset", typst.Length("8pt")),
+		typst.Named("align", typst.Op("+", typst.Ident("center"), typst.Ident("horizon"))),
+		typst.Group(label("课程名称"), value(info.Course), label("授课班级"), value(info.Class), label("授课教师"), value(info.Teacher)),
+		typst.Group(label("授课日期"), value(displayDate(info.Date)), label("授课地点"), value(info.Location), label("总课时"), value(info.TotalHours)),
+		typst.Group(label("教学目标"), wide(info.Objectives)),
+		typst.Group(label("教学重难点"), wide(info.KeyPoints)),
+		typst.Group(label("教学资源"), wide(info.Resources)),
+	)
+	return "\n" + string(typst.Embed(tbl)) + "\n"
+}
@@ -0,0 +1,133 @@
+package jiaoan
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+)
+
+// minutesPerHour 是一学时的分钟数，用于换算以分钟写出的课时
+const minutesPerHour = 45
+
+// hoursRe 匹配课时写法：0.5H、1h、2学时、45分钟、90min，不写单位时按学时计
+var hoursRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(H|h|学时|分钟|min)?$`)
+
+// parseHours 将课时写法换算为学时数
+func parseHours(s string) (float64, bool) {
+	m := hoursRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	if m[2] == "分钟" || m[2] == "min" {
+		n /= minutesPerHour
+	}
+	return n, true
+}
+
+// formatHours 按课时分配列的写法输出学时数，如 1.5H
+func formatHours(h float64) string {
+	return formatHoursIn(h, "H")
+}
+
+// formatHoursIn 以 unit（H、学时或分钟）输出学时数
+func formatHoursIn(h float64, unit string) string {
+	if unit == "分钟" {
+		return strconv.FormatFloat(math.Round(h*minutesPerHour), 'f', -1, 64) + unit
+	}
+	if unit != "学时" {
+		unit = "H"
+	}
+	return strconv.FormatFloat(math.Round(h*100)/100, 'f', -1, 64) + unit
+}
+
+// hours 返回活动下各五级标题的课时之和，无法识别的课时不计入
+func (b H4Block) hours() float64 {
+	var sum float64
+	for _, h5 := range b.H5Blocks {
+		if h, ok := parseHours(h5.Title); ok {
+			sum += h
+		}
+	}
+	return sum
+}
+
+// hours 返回学习环节下各活动的课时之和
+func (t Table) hours() float64 {
+	var sum float64
+	for _, h4 := range t.H4Blocks {
+		sum += h4.hours()
+	}
+	return sum
+}
+
+// hours 返回内容区域下各学习环节的课时之和
+func (s DocumentSection) hours() float64 {
+	var sum float64
+	for _, table := range s.Tables {
+		sum += table.hours()
+	}
+	return sum
+}
+
+// checkTotalHours 核对 front matter 中的总课时与各五级标题课时之和；
+// line 是总课时在输入中的行号。不符时报错：表格照常生成，但教务审核
+// 不接受课时对不上的教案
+func checkTotalHours(declared string, line int, sections []DocumentSection) []cli.Diagnostic {
+	if declared == "" {
+		return nil
+	}
+	want, ok := parseHours(declared)
+	if !ok {
+		return []cli.Diagnostic{cli.Warningf(line, 1, "class-hours-invalid", "无法识别总课时 %q，应写作 4H、4学时或 180分钟", declared)}
+	}
+	var got float64
+	for _, section := range sections {
+		got += section.hours()
+	}
+	if math.Abs(got-want) > 0.005 {
+		return []cli.Diagnostic{cli.Errorf(line, 1, "class-hours-mismatch", "总课时为 %s，但各活动课时合计 %s", formatHours(want), formatHours(got))}
+	}
+	return nil
+}
+
+// hoursCell 返回课时列中五级标题的显示文字：设置了单位时换算，否则原样输出
+func (l Layout) hoursCell(title string) string {
+	unit := l.Columns[l.hoursColumn()].Unit
+	if h, ok := parseHours(title); ok && unit != "" {
+		return formatHoursIn(h, unit)
+	}
+	return title
+}
+
+// addHoursRow 在表格中添加合计行：合计课时位于课时列，标签占据其前的各列并
+// 右对齐（课时列在首列时占据其后的各列）。没有课时列时不添加
+func (l Layout) addHoursRow(tbl *typst.CallExpr, label string, h float64, bold bool) {
+	hc := l.hoursColumn()
+	if hc < 0 {
+		return
+	}
+	text, total := typst.Text(label), typst.Text(formatHoursIn(h, l.Columns[hc].Unit))
+	if bold {
+		text, total = "*"+text+"*", "*"+total+"*"
+	}
+	before, after := hc, len(l.Columns)-hc-1
+	var row []typst.Arg
+	if before > 0 {
+		row = append(row, typst.Pos(typst.TableCell(text, typst.Named("colspan", typst.Int(before)), typst.Named("align", typst.Ident("right")))))
+	}
+	row = append(row, typst.Pos(typst.Content(total)))
+	if before == 0 {
+		row = append(row, typst.Pos(typst.TableCell(text, typst.Named("colspan", typst.Int(after)), typst.Named("align", typst.Ident("left")))))
+	} else if after > 0 {
+		row = append(row, typst.Pos(typst.TableCell("", typst.Named("colspan", typst.Int(after)))))
+	}
+	tbl.Arg(typst.Group(row...))
+}
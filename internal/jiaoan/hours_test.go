@@ -0,0 +1,66 @@
+package jiaoan
+
+import (
+	"testing"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+)
+
+func TestParseHours(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"1H", 1, true},
+		{"0.5h", 0.5, true},
+		{" 2 学时 ", 2, true},
+		{"45分钟", 1, true},
+		{"90min", 2, true},
+		{"3", 3, true},
+		{"1.5 H", 1.5, true},
+		{"", 0, false},
+		{"同上", 0, false},
+		{"-1H", 0, false},
+		{"1小时", 0, false},
+		{"H", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHours(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseHours(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCheckTotalHours(t *testing.T) {
+	// 两个学习环节共 0.5 + 1 + 45分钟 = 2.5 学时；无法识别的课时不计入
+	sections := []DocumentSection{{Tables: []Table{
+		{H4Blocks: []H4Block{{H5Blocks: []H5Block{{Title: "0.5H"}, {Title: "1H"}}}}},
+		{H4Blocks: []H4Block{{H5Blocks: []H5Block{{Title: "45分钟"}, {Title: "同上"}}}}},
+	}}}
+	tests := []struct {
+		declared string
+		code     string // "" if nothing is reported
+		severity cli.Severity
+	}{
+		{"", "", ""},
+		{"2.5H", "", ""},
+		{"2.5学时", "", ""},
+		{"112.5分钟", "", ""},
+		{"3H", "class-hours-mismatch", cli.SeverityError},
+		{"很多", "class-hours-invalid", cli.SeverityWarning},
+	}
+	for _, tt := range tests {
+		diags := checkTotalHours(tt.declared, 4, sections)
+		if tt.code == "" {
+			if len(diags) != 0 {
+				t.Errorf("checkTotalHours(%q) = %v, want none", tt.declared, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || diags[0].Code != tt.code || diags[0].Severity != tt.severity || diags[0].Line != 4 {
+			t.Errorf("checkTotalHours(%q) = %v, want one %s %s on line 4", tt.declared, diags, tt.severity, tt.code)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package jiaoan
+
+import "github.com/Presto-io/presto-official-templates/internal/typst"
+
+// cellImageDef 定义单元格图片函数，写在模板 preamble 之后
+const cellImageDef = `
+// 单元格图片：按原始大小显示，但宽度不超过所在列，保持原始比例
+#let cell-image(path, max-width, alt: none) = context {
+  let width = measure(image(path)).width
+  image(path, width: if width > max-width { max-width } else { width }, alt: alt)
+}
+`
+
+// cellImage 生成单元格内的图片，按原始大小显示但不超过 maxWidth
+func cellImage(path, alt string, maxWidth typst.Expr) *typst.CallExpr {
+	call := typst.Call("cell-image", typst.Pos(typst.Str(path)), typst.Pos(maxWidth))
+	if alt != "" {
+		call.Arg(typst.Named("alt", typst.Str(alt)))
+	}
+	return call
+}
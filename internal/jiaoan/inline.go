@@ -0,0 +1,172 @@
+package jiaoan
+
+import (
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// cellParser 只识别段落和行内语法：单元格内容中以 "1."、"-"、"#" 开头的行
+// 仍是普通文字，不会被解析为列表或标题
+var cellParser = parser.NewParser(
+	parser.WithBlockParsers(util.Prioritized(parser.NewParagraphParser(), 100)),
+	parser.WithInlineParsers(parser.DefaultInlineParsers()...),
+)
+
+// cellRenderer 渲染一个单元格的行内 Markdown
+type cellRenderer struct {
+	source   []byte
+	maxWidth typst.Expr // 单元格中图片的最大宽度
+}
+
+// cellLine 是单元格中的一行 Typst 标记；Figure 表示该行只有图片，编号时跳过
+type cellLine struct {
+	Markup typst.Markup
+	Figure bool
+}
+
+// joinLines 以 sep 连接单元格各行的标记
+func joinLines(lines []cellLine, sep string) typst.Markup {
+	parts := make([]string, len(lines))
+	for i, l := range lines {
+		parts[i] = string(l.Markup)
+	}
+	return typst.Markup(strings.Join(parts, sep))
+}
+
+// renderCell 将单元格内容按行内 Markdown 渲染为 Typst 标记。每个源行输出为
+// 一行，供 formatNumberedContent 编号；行尾的硬换行（反斜杠或两个空格）
+// 在同一编号项内换行。只有图片的行输出为不编号的图片，宽度不超过 maxWidth
+func renderCell(content string, maxWidth typst.Expr) []cellLine {
+	r := cellRenderer{source: []byte(content), maxWidth: maxWidth}
+	doc := cellParser.Parse(text.NewReader(r.source))
+	var lines []cellLine
+	for para := doc.FirstChild(); para != nil; para = para.NextSibling() {
+		var line []ast.Node
+		for n := para.FirstChild(); n != nil; n = n.NextSibling() {
+			line = append(line, n)
+			if t, ok := n.(*ast.Text); ok && t.SoftLineBreak() && !t.HardLineBreak() {
+				lines = append(lines, r.line(line))
+				line = nil
+			}
+		}
+		lines = append(lines, r.line(line))
+	}
+	return lines
+}
+
+// line 渲染一个源行的行内节点
+func (r cellRenderer) line(nodes []ast.Node) cellLine {
+	if figures, ok := r.figures(nodes); ok {
+		return cellLine{Markup: figures, Figure: true}
+	}
+	var buf strings.Builder
+	for _, n := range nodes {
+		if t, ok := n.(*ast.Text); ok && t.SoftLineBreak() && !t.HardLineBreak() {
+			// 行尾的软换行由调用方分行
+			buf.WriteString(typst.EscapeContent(string(t.Segment.Value(r.source))))
+			continue
+		}
+		buf.WriteString(r.inline(n))
+	}
+	return cellLine{Markup: typst.Markup(buf.String())}
+}
+
+// figures 在一行只有图片（及空白）时返回以空格分隔的图片
+func (r cellRenderer) figures(nodes []ast.Node) (typst.Markup, bool) {
+	var images []string
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.Image:
+			images = append(images, string(typst.Embed(r.image(n))))
+		case *ast.Text:
+			if strings.TrimSpace(string(n.Segment.Value(r.source))) != "" || n.HardLineBreak() {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	return typst.Markup(strings.Join(images, " ")), len(images) > 0
+}
+
+// image 生成图片调用，以图片说明文字作为 alt
+func (r cellRenderer) image(img *ast.Image) typst.Expr {
+	var alt strings.Builder
+	_ = ast.Walk(img, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := n.(*ast.Text); ok && entering {
+			alt.Write(t.Segment.Value(r.source))
+		}
+		return ast.WalkContinue, nil
+	})
+	return cellImage(string(img.Destination), alt.String(), r.maxWidth)
+}
+
+// inline 渲染单个行内节点；嵌套在强调、链接中的软换行输出为空格
+func (r cellRenderer) inline(n ast.Node) string {
+	source := r.source
+	switch n := n.(type) {
+	case *ast.Text:
+		s := typst.EscapeContent(string(n.Segment.Value(source)))
+		if n.HardLineBreak() {
+			s += ` \ `
+		} else if n.SoftLineBreak() {
+			s += " "
+		}
+		return s
+
+	case *ast.String:
+		return typst.EscapeContent(string(n.Value))
+
+	case *ast.CodeSpan:
+		var code strings.Builder
+		for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+			if t, ok := child.(*ast.Text); ok {
+				code.Write(t.Segment.Value(source))
+			}
+		}
+		return string(typst.Inline(typst.Raw(code.String(), "", false)))
+
+	case *ast.Emphasis:
+		fn := "emph"
+		if n.Level == 2 {
+			fn = "strong"
+		}
+		return string(typst.Inline(typst.Call(fn).Body(r.inlines(n))))
+
+	case *ast.Link:
+		call := typst.Call("link", typst.Pos(typst.Str(string(n.Destination)))).Body(r.inlines(n))
+		return string(typst.Inline(call))
+
+	case *ast.AutoLink:
+		return string(typst.Inline(typst.Call("link", typst.Pos(typst.Str(string(n.URL(source)))))))
+
+	case *ast.Image:
+		return string(typst.Inline(r.image(n)))
+
+	case *ast.RawHTML:
+		// 单元格不支持 HTML，按原文输出
+		var raw strings.Builder
+		for i := 0; i < n.Segments.Len(); i++ {
+			seg := n.Segments.At(i)
+			raw.Write(seg.Value(source))
+		}
+		return typst.EscapeContent(raw.String())
+
+	default:
+		return string(r.inlines(n))
+	}
+}
+
+// inlines 渲染节点的全部行内子节点
+func (r cellRenderer) inlines(n ast.Node) typst.Markup {
+	var buf strings.Builder
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		buf.WriteString(r.inline(child))
+	}
+	return typst.Markup(buf.String())
+}
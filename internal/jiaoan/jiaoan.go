@@ -0,0 +1,263 @@
+// Package jiaoan converts lesson-plan (教案) Markdown into Typst tables. It is
+// shared by the jiaoan-shicao and jiaoan-lilun templates, which differ only in
+// their page setup and default table layout.
+//
+// The body is a heading hierarchy: each "##" starts a section, each "###" a
+// table block (学习环节——学习单元), each "####" an activity and each "#####" a
+// row whose title is its class hours. The blocks below a "#####" fill the
+// content columns, by label or in order.
+package jiaoan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+)
+
+// Convert 将带 front matter 的教案 Markdown 转换为 Typst：pageSetup 是模板的
+// 页面设置（#set page(...)），defaults 是 front matter 未配置 表格列 等时使用的表格布局。
+// 诊断按行号排序
+func Convert(input string, schema frontmatter.Schema, pageSetup string, defaults Layout) (string, []cli.Diagnostic) {
+	doc, diags := frontmatter.Parse(input, schema)
+	layout, layoutDiags := parseLayout(doc, defaults)
+	diags = append(diags, layoutDiags...)
+	sections, bodyDiags := parseMarkdown(doc.Body, doc.BodyLine, layout)
+	info := parseLessonInfo(doc)
+	diags = append(diags, bodyDiags...)
+	diags = append(diags, checkTotalHours(info.TotalHours, doc.Lines["总课时"], sections)...)
+	output, mergeDiags := generateTypst(pageSetup, info, layout, sections)
+	diags = append(diags, mergeDiags...)
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Line < diags[j].Line })
+	return output, diags
+}
+
+// Paragraph 存储五级标题下的一个内容块及其在输入中的行号
+type Paragraph struct {
+	Label  string // 内容标签对应的列名，未写标签时为空
+	Text   string
+	Line   int
+	Joined bool // 与上一内容块之间没有空行，如紧跟段落的列表
+}
+
+// H5Block 存储五级标题及其内容，标题为该行的课时
+type H5Block struct {
+	Title   string
+	Line    int // 标题在输入中的行号，0 表示未知
+	Content []Paragraph
+	Fields  map[string]string // 按列名归集的内容，见 resolveFields
+}
+
+// H4Block 存储四级标题及其下的所有五级标题块
+type H4Block struct {
+	Title    string
+	H5Blocks []H5Block
+}
+
+// Table 存储一个三级标题定义的表格
+type Table struct {
+	H3Part1  string
+	H3Part2  string
+	H4Blocks []H4Block
+}
+
+// DocumentSection 存储一个二级标题定义的内容区域
+type DocumentSection struct {
+	H2Title string
+	Tables  []Table
+}
+
+// generateTypst 根据课程信息、表格布局和解析出的结构体生成 typst 格式字符串，
+// 并报告无法合并的 "同上" 单元格
+func generateTypst(pageSetup string, info LessonInfo, layout Layout, sections []DocumentSection) (string, []cli.Diagnostic) {
+	var sb strings.Builder
+	var diags []cli.Diagnostic
+	sb.WriteString(preamble(pageSetup))
+	sb.WriteString(cellImageDef)
+	sb.WriteString(renderLessonHeader(info))
+
+	cols := len(layout.Columns)
+	for _, section := range sections {
+		sb.WriteString("\n" + string(typst.Heading(2, typst.Text(section.H2Title))) + "\n\n")
+
+		if len(section.Tables) > 0 {
+			tbl := typst.Call("table",
+				typst.Named("columns", layout.columnsExpr()),
+				typst.Named("stroke", typst.Length("0.5pt")),
+				typst.Named("align", typst.Op("+", typst.Ident("center"), typst.Ident("horizon"))),
+			)
+
+			for _, table := range section.Tables {
+				// 表格第一行
+				tbl.Arg(typst.Group(
+					typst.Pos(typst.Content("*"+typst.Text(layout.StageLabel)+"*")),
+					typst.Pos(typst.Content("*"+typst.Text(table.H3Part1)+"*")),
+					typst.Pos(typst.Content("*"+typst.Text(layout.UnitLabel)+"*")),
+					typst.Pos(typst.TableCell("*"+typst.Text(table.H3Part2)+"*", typst.Named("colspan", typst.Int(cols-3)))),
+				))
+
+				// 表格第二行
+				var header []typst.Arg
+				for _, col := range layout.Columns {
+					header = append(header, typst.Pos(typst.Content(typst.Text(col.Name))))
+				}
+				tbl.Arg(typst.Group(header...))
+
+				h4Counter := 1 // Reset for each table (H3)
+
+				// 为整个表格构建单元格原文矩阵，活动列单独输出；"同上" 可跨活动合并
+				var h4s []H4Block
+				var rows []H5Block
+				var rowH4 []int // 每行所属活动在 h4s 中的序号
+				for _, h4 := range table.H4Blocks {
+					if len(h4.H5Blocks) == 0 {
+						continue
+					}
+					h4s = append(h4s, h4)
+					for _, h5 := range h4.H5Blocks {
+						rows = append(rows, h5)
+						rowH4 = append(rowH4, len(h4s)-1)
+					}
+				}
+				raw := make([][]string, len(rows))
+				for i, h5 := range rows {
+					raw[i] = make([]string, cols)
+					for col, c := range layout.Columns {
+						switch c.Source {
+						case SourceActivity:
+						case SourceHours:
+							raw[i][col] = h5.Title
+						default:
+							raw[i][col] = h5.Fields[c.Source]
+						}
+					}
+				}
+
+				rowspans, orphans := mergeCells(raw, func(col int) bool { return layout.Columns[col].Source != SourceActivity })
+				for _, o := range orphans {
+					h5 := rows[o[0]]
+					diags = append(diags, cli.Warningf(h5.Line, 1, "merge-orphan", "##### %s 的 %s 写了 %q，但上方没有可合并的单元格，已留空", h5.Title, layout.Columns[o[1]].Name, strings.TrimSpace(raw[o[0]][o[1]])))
+					raw[o[0]][o[1]] = ""
+				}
+
+				cellContents := make([][][]cellLine, len(rows))
+				for i, h5 := range rows {
+					cellContents[i] = make([][]cellLine, cols)
+					for col, c := range layout.Columns {
+						switch {
+						case c.Source == SourceActivity || rowspans[i][col] == 0:
+						case c.Source == SourceHours && raw[i][col] != "":
+							cellContents[i][col] = []cellLine{{Markup: typst.Text(layout.hoursCell(h5.Title))}}
+						default:
+							cellContents[i][col] = renderCell(raw[i][col], layout.imageMaxWidth(col))
+						}
+					}
+				}
+
+				// 输出每一行，依据 rowspans 决定是否输出或输出带 rowspan 的单元格
+				var counters []int
+				var numberedH4Title string
+				var pendingHours float64 // 尚未输出小计的活动课时
+				pendingFrom := h4Counter // 尚未输出小计的第一个活动的序号
+				for i := range rows {
+					h4 := h4s[rowH4[i]]
+					first := i == 0 || rowH4[i-1] != rowH4[i]
+					if first {
+						numberedH4Title = fmt.Sprintf("%d. %s", h4Counter, typst.EscapeContent(h4.Title))
+						h4Counter++
+						// 为每列在输出时维护独立序号计数器（H4 内重置）
+						counters = make([]int, cols)
+						for col := range counters {
+							counters[col] = 1
+						}
+					}
+
+					var row []typst.Arg
+					for col, c := range layout.Columns {
+						// 活动列（H4 标题）只在第一行输出，并带有整体 rowspan
+						if c.Source == SourceActivity {
+							if first {
+								title := numberedH4Title
+								if !c.Numbered {
+									title = typst.EscapeContent(h4.Title)
+								}
+								row = append(row, typst.Pos(typst.TableCell(typst.Markup(title), typst.Named("rowspan", typst.Int(len(h4.H5Blocks))))))
+							}
+							continue
+						}
+
+						rs := rowspans[i][col]
+						if rs == 0 {
+							// 被上方合并，跳过输出该单元格
+							continue
+						}
+
+						var body typst.Markup
+						if c.Numbered {
+							body, counters[col] = formatNumberedContent(cellContents[i][col], counters[col])
+						} else {
+							// 不编号的列，各行以空行分段
+							body = joinLines(cellContents[i][col], "\n\n")
+						}
+						left := c.Numbered && strings.TrimSpace(string(body)) != ""
+
+						// 仅在 rowspan > 1 时使用 table.cell
+						if rs > 1 {
+							attrs := []typst.Arg{typst.Named("rowspan", typst.Int(rs))}
+							if left {
+								attrs = append(attrs, typst.Named("align", typst.Ident("left")))
+							}
+							row = append(row, typst.Pos(typst.TableCell(body, attrs...)))
+						} else if left {
+							// rowspan == 1 时，不使用 table.cell，对齐通过 align() 包裹
+							row = append(row, typst.Pos(typst.Call("align", typst.Pos(typst.Ident("left"))).Body(body)))
+						} else {
+							row = append(row, typst.Pos(typst.Content(body)))
+						}
+					}
+					tbl.Arg(typst.Group(row...))
+
+					// 活动结束时输出小计；有单元格跨入下一活动时无法在两者之间插入
+					// 小计行，这些活动合用一行，标明所含活动的序号
+					if last := i == len(rows)-1 || rowH4[i+1] != rowH4[i]; last {
+						pendingHours += h4.hours()
+						if spansBoundary(rowspans, i) {
+							diags = append(diags, cli.Warningf(rows[i].Line, 1, "merge-subtotal", "活动 %d 与下一活动之间有合并的单元格，两者的活动小计合为一行", h4Counter-1))
+							continue
+						}
+						label := "活动小计"
+						if pendingFrom < h4Counter-1 {
+							label = fmt.Sprintf("活动 %d～%d 小计", pendingFrom, h4Counter-1)
+						}
+						layout.addHoursRow(tbl, label, pendingHours, false)
+						pendingHours = 0
+						pendingFrom = h4Counter
+					}
+				}
+				layout.addHoursRow(tbl, layout.StageLabel+"合计", table.hours(), true)
+			}
+			layout.addHoursRow(tbl, "总计", section.hours(), true)
+			sb.WriteString(string(typst.Embed(tbl)) + "\n")
+		}
+	}
+	return sb.String(), diags
+}
+
+// formatNumberedContent formats content with numbering for each line.
+// Lines holding only images are kept as they are.
+func formatNumberedContent(lines []cellLine, startCounter int) (typst.Markup, int) {
+	var formattedLines []string
+	counter := startCounter
+	for _, line := range lines {
+		if line.Figure {
+			formattedLines = append(formattedLines, string(line.Markup))
+		} else if strings.TrimSpace(string(line.Markup)) != "" {
+			formattedLines = append(formattedLines, fmt.Sprintf("%d. %s；", counter, line.Markup))
+			counter++
+		}
+	}
+	return typst.Markup(strings.Join(formattedLines, "\n")), counter
+}
@@ -0,0 +1,168 @@
+package jiaoan
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
+	"github.com/Presto-io/presto-official-templates/internal/typst"
+)
+
+// cellInsetCM 是单元格左右内边距之和（table 默认 inset 5pt）
+const cellInsetCM = 2 * 5 * 2.54 / 72
+
+// 列的特殊内容来源；其余来源是五级标题下的内容标签
+const (
+	SourceActivity = "活动" // 四级标题，跨该活动的所有行
+	SourceHours    = "课时" // 五级标题
+)
+
+// Column 描述活动表格的一列
+type Column struct {
+	Name     string // 表头文字
+	Width    string // 列宽，如 2.3cm、1fr 或 auto
+	Source   string // 内容来源：活动、课时或内容标签
+	Numbered bool   // 是否逐行编号；不编号时各行分段显示
+	Unit     string // 课时列的显示单位：H、学时或分钟；为空时原样显示五级标题
+}
+
+// Layout 是活动表格的列定义和三级标题行的标签
+type Layout struct {
+	Columns     []Column
+	StageLabel  string  // 三级标题前半部分的标签
+	UnitLabel   string  // 三级标题后半部分的标签
+	TextWidthCM float64 // 版心宽度，用于计算 auto 列中图片的最大宽度，须与模板的 page 设置一致
+}
+
+// minColumns 是三级标题行（两个标签及其内容）所需的最少列数
+const minColumns = 4
+
+// parseLayout 从 front matter 的 表格列、环节标签、单元标签 读取表格布局，
+// 未配置的部分使用模板的 defaults
+func parseLayout(doc frontmatter.Document, defaults Layout) (Layout, []cli.Diagnostic) {
+	var diags []cli.Diagnostic
+	layout := defaults
+	if s := doc.String("环节标签"); s != "" {
+		layout.StageLabel = s
+	}
+	if s := doc.String("单元标签"); s != "" {
+		layout.UnitLabel = s
+	}
+
+	items, _ := doc.Values["表格列"].([]interface{})
+	if len(items) == 0 {
+		return layout, nil
+	}
+	line := doc.Lines["表格列"]
+	var columns []Column
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		col := Column{Name: mapString(m, "名称"), Width: mapString(m, "宽度"), Source: mapString(m, "内容"), Unit: mapString(m, "单位")}
+		col.Numbered, _ = m["编号"].(bool)
+		if col.Source == "" {
+			col.Source = col.Name
+		}
+		if col.Width == "" {
+			col.Width = "auto"
+		} else if !validWidth(col.Width) {
+			diags = append(diags, cli.Warningf(line, 1, "layout-width", "表格列 %s 的宽度 %q 无效，已改为 auto", col.Name, col.Width))
+			col.Width = "auto"
+		}
+		columns = append(columns, col)
+	}
+	if len(columns) < minColumns {
+		diags = append(diags, cli.Warningf(line, 1, "layout-columns", "表格列至少需要 %d 列，已使用默认布局", minColumns))
+		return layout, diags
+	}
+	layout.Columns = columns
+	return layout, diags
+}
+
+// validWidth 判断列宽是否可用：auto，或不为负的长度、百分比、fr
+func validWidth(width string) bool {
+	if width == "auto" {
+		return true
+	}
+	_, ok := typst.ParseLength(width)
+	return ok && !strings.HasPrefix(width, "-")
+}
+
+// mapString 返回 front matter 对象中 key 的文字
+func mapString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// fields 按列的顺序返回内容标签；不写标签时五级标题下的段落依次填入这些列
+func (l Layout) fields() []string {
+	var fields []string
+	for _, col := range l.Columns {
+		if col.Source != SourceActivity && col.Source != SourceHours {
+			fields = append(fields, col.Source)
+		}
+	}
+	return fields
+}
+
+// hoursColumn 返回课时列的序号，没有课时列时返回 -1
+func (l Layout) hoursColumn() int {
+	for i, col := range l.Columns {
+		if col.Source == SourceHours {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnsExpr 返回活动表格的 columns 参数
+func (l Layout) columnsExpr() typst.Expr {
+	items := make([]typst.Expr, len(l.Columns))
+	for i, col := range l.Columns {
+		if col.Width == "auto" {
+			items[i] = typst.Auto
+		} else {
+			items[i] = typst.Length(col.Width)
+		}
+	}
+	return typst.Array(items...)
+}
+
+// absoluteCM 将绝对长度或版心百分比换算为厘米；auto、fr、em 返回 false
+func (l Layout) absoluteCM(width string) (float64, bool) {
+	units := []struct {
+		suffix string
+		cm     float64
+	}{{"cm", 1}, {"mm", 0.1}, {"in", 2.54}, {"pt", 2.54 / 72}, {"%", l.TextWidthCM / 100}}
+	for _, u := range units {
+		if strings.HasSuffix(width, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(width, u.suffix), 64)
+			return n * u.cm, err == nil
+		}
+	}
+	return 0, false
+}
+
+// imageMaxWidth 返回第 col 列中图片的最大宽度：列宽减去内边距。auto 和 fr
+// 列平分固定列以外的版心宽度
+func (l Layout) imageMaxWidth(col int) typst.Expr {
+	fixed, shared := 0.0, 0
+	for _, c := range l.Columns {
+		if cm, ok := l.absoluteCM(c.Width); ok {
+			fixed += cm
+		} else {
+			shared++
+		}
+	}
+	width, ok := l.absoluteCM(l.Columns[col].Width)
+	if !ok {
+		width = (l.TextWidthCM - fixed) / float64(shared)
+	}
+	if width -= cellInsetCM; width < 1 {
+		width = 1
+	}
+	return typst.Length(strconv.FormatFloat(width, 'f', 2, 64) + "cm")
+}
@@ -0,0 +1,45 @@
+package jiaoan
+
+import (
+	"testing"
+
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
+)
+
+func TestParseLayoutWidths(t *testing.T) {
+	tests := []struct {
+		width string
+		want  string
+		warn  bool
+	}{
+		{"2.3cm", "2.3cm", false},
+		{"30%", "30%", false},
+		{"1fr", "1fr", false},
+		{"auto", "auto", false},
+		{"", "auto", false},
+		{"-5cm", "auto", true},
+		{"-1fr", "auto", true},
+		{"-10%", "auto", true},
+		{"wide", "auto", true},
+	}
+	for _, tt := range tests {
+		columns := []interface{}{
+			map[string]interface{}{"名称": "教学活动", "内容": SourceActivity},
+			map[string]interface{}{"名称": "学习内容", "宽度": tt.width},
+			map[string]interface{}{"名称": "学生活动"},
+			map[string]interface{}{"名称": "课时", "内容": SourceHours},
+		}
+		doc := frontmatter.Document{
+			Values: map[string]interface{}{"表格列": columns},
+			Lines:  map[string]int{"表格列": 3},
+		}
+		layout, diags := parseLayout(doc, testLayout)
+		if got := layout.Columns[1].Width; got != tt.want {
+			t.Errorf("width %q: got %q, want %q", tt.width, got, tt.want)
+		}
+		warned := len(diags) == 1 && diags[0].Code == "layout-width" && diags[0].Line == 3
+		if warned != tt.warn || len(diags) > 1 {
+			t.Errorf("width %q: diagnostics %v, want layout-width warning = %v", tt.width, diags, tt.warn)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package jiaoan
+
+import "strings"
+
+// mergeMarkers 是要求与正上方单元格合并的单元格内容
+var mergeMarkers = []string{"同上", "{merge}"}
+
+// isMergeMarker 判断单元格是否只写了合并标记；与其他文字写在一起时不合并
+func isMergeMarker(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, m := range mergeMarkers {
+		if s == m {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeCells 计算单元格的 rowspan：写了合并标记的单元格并入正上方最近的
+// 起始单元格（可连续合并），其 rowspan 记为 0，输出时跳过。mergeable 为
+// false 的列不参与合并。返回的 orphans 是首行或上方没有可合并单元格的位置
+// {row, col}，其 rowspan 保持 1；孤立的标记不能作为合并目标，其下方的
+// 标记同样孤立
+func mergeCells(cells [][]string, mergeable func(col int) bool) (rowspans [][]int, orphans [][2]int) {
+	rowspans = make([][]int, len(cells))
+	for i := range cells {
+		rowspans[i] = make([]int, len(cells[i]))
+		for j := range rowspans[i] {
+			rowspans[i][j] = 1
+		}
+	}
+	isOrphan := map[[2]int]bool{}
+	for i := range cells {
+		for col, text := range cells[i] {
+			if !mergeable(col) || !isMergeMarker(text) {
+				continue
+			}
+			k := i - 1
+			for k >= 0 && rowspans[k][col] == 0 {
+				k--
+			}
+			if k < 0 || isOrphan[[2]int{k, col}] {
+				orphans = append(orphans, [2]int{i, col})
+				isOrphan[[2]int{i, col}] = true
+				continue
+			}
+			rowspans[k][col]++
+			rowspans[i][col] = 0
+		}
+	}
+	return rowspans, orphans
+}
+
+// spansBoundary 判断是否有合并的单元格跨越第 row 行与下一行之间的边界
+func spansBoundary(rowspans [][]int, row int) bool {
+	for col := range rowspans[row] {
+		k := row
+		for k >= 0 && rowspans[k][col] == 0 {
+			k--
+		}
+		if k >= 0 && k+rowspans[k][col]-1 > row {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package jiaoan
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 测试中第 0 列是活动列，不参与合并；第 1 列是内容列
+func TestMergeCells(t *testing.T) {
+	tests := []struct {
+		name     string
+		cells    [][]string
+		rowspans [][]int
+		orphans  [][2]int
+	}{
+		{
+			name:     "within one activity",
+			cells:    [][]string{{"", "讲解"}, {"", "同上"}, {"", "练习"}},
+			rowspans: [][]int{{1, 2}, {1, 0}, {1, 1}},
+		},
+		{
+			name:     "across activities",
+			cells:    [][]string{{"", "讲解"}, {"", " 同上 "}, {"", "{merge}"}},
+			rowspans: [][]int{{1, 3}, {1, 0}, {1, 0}},
+		},
+		{
+			name:     "chain starting with marker",
+			cells:    [][]string{{"", "同上"}, {"", "同上"}, {"", "讲解"}, {"", "同上"}},
+			rowspans: [][]int{{1, 1}, {1, 1}, {1, 2}, {1, 0}},
+			orphans:  [][2]int{{0, 1}, {1, 1}},
+		},
+		{
+			name:     "marker with other text",
+			cells:    [][]string{{"", "讲解"}, {"", "同上，并演示"}},
+			rowspans: [][]int{{1, 1}, {1, 1}},
+		},
+		{
+			name:     "column not mergeable",
+			cells:    [][]string{{"讲解", ""}, {"同上", ""}},
+			rowspans: [][]int{{1, 1}, {1, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rowspans, orphans := mergeCells(tt.cells, func(col int) bool { return col != 0 })
+			if !reflect.DeepEqual(rowspans, tt.rowspans) {
+				t.Errorf("rowspans = %v, want %v", rowspans, tt.rowspans)
+			}
+			if !reflect.DeepEqual(orphans, tt.orphans) {
+				t.Errorf("orphans = %v, want %v", orphans, tt.orphans)
+			}
+		})
+	}
+}
+
+func TestSpansBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		rowspans [][]int
+		want     []bool // 每行与下一行之间是否有合并的单元格
+	}{
+		{"no merges", [][]int{{1, 1}, {1, 1}}, []bool{false, false}},
+		{"within one activity", [][]int{{1, 2}, {1, 0}, {1, 1}}, []bool{true, false, false}},
+		{"across activities", [][]int{{1, 3}, {1, 0}, {1, 0}, {1, 1}}, []bool{true, true, false, false}},
+		{"orphan chain", [][]int{{1, 1}, {1, 1}, {1, 2}, {1, 0}}, []bool{false, false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for row, want := range tt.want {
+				if got := spansBoundary(tt.rowspans, row); got != want {
+					t.Errorf("spansBoundary(row %d) = %v, want %v", row, got, want)
+				}
+			}
+		})
+	}
+}
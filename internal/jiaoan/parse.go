@@ -0,0 +1,219 @@
+package jiaoan
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// orphanHeading 报告缺少上级标题而被忽略的标题行
+func orphanHeading(lineNo int, level, parent string) cli.Diagnostic {
+	return cli.Warningf(lineNo, 1, "orphan-heading", "%s 标题前没有 %s 标题，已忽略", level, parent)
+}
+
+// h3Separators 是三级标题中学习环节与学习单元的分隔符，按长度从长到短尝试
+var h3Separators = []string{"——", "—", " - ", "-"}
+
+// splitH3Title 将三级标题拆分为学习环节和学习单元
+func splitH3Title(title string) (string, string) {
+	for _, sep := range h3Separators {
+		if parts := strings.SplitN(title, sep, 2); len(parts) == 2 {
+			return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		}
+	}
+	return title, ""
+}
+
+// blockParser 解析教案正文；定义列表用于 "学习内容\n: …" 形式的内容标签
+var blockParser = goldmark.New(goldmark.WithExtensions(extension.DefinitionList)).Parser()
+
+// mdParser 从 goldmark 语法树中读取标题层级和内容块
+type mdParser struct {
+	source    []byte
+	firstLine int      // source 在输入中的起始行号
+	fields    []string // 内容标签，见 Layout.fields
+	diags     []cli.Diagnostic
+}
+
+// parseMarkdown 将 markdown 字符串解析为 DocumentSection 结构体切片，
+// 并报告因缺少上级标题而被忽略的标题；firstLine 是 content 在输入中的起始行号，
+// layout 决定五级标题下内容对应的列，有课时列时检查五级标题的课时写法。
+// 二至五级标题构成层级，五级标题之后的每个块级元素（段落、列表、代码块等）
+// 是一个内容块；代码块中的 "#" 行和 HTML 注释不会被误读。内容块随后由
+// resolveFields 按标签或顺序归集到各列，不带标签时中间没有空行的块合为一段
+func parseMarkdown(content string, firstLine int, layout Layout) ([]DocumentSection, []cli.Diagnostic) {
+	fields := layout.fields()
+	checkHours := layout.hoursColumn() >= 0
+	p := &mdParser{source: []byte(content), firstLine: firstLine, fields: fields}
+	doc := blockParser.Parse(text.NewReader(p.source))
+
+	var sections []DocumentSection
+	var currentSection *DocumentSection
+	var currentTable *Table
+	var currentH4 *H4Block
+	var currentH5 *H5Block
+	prevEnd := 0 // 当前五级标题下上一内容块最后一行的行号
+
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		h, ok := n.(*ast.Heading)
+		if !ok || h.Level < 2 || h.Level > 5 {
+			if currentH5 == nil || n.Kind() == ast.KindHTMLBlock || n.Kind() == ast.KindThematicBreak {
+				continue
+			}
+			paras := p.paragraphs(n)
+			if len(paras) > 0 && prevEnd > 0 {
+				paras[0].Joined = !p.blankBetween(prevEnd, p.line(n))
+			}
+			if end := p.lastLine(n); end > 0 {
+				prevEnd = end
+			}
+			currentH5.Content = append(currentH5.Content, paras...)
+			continue
+		}
+		prevEnd = 0
+
+		title := p.blockText(h)
+		switch h.Level {
+		case 2:
+			sections = append(sections, DocumentSection{H2Title: title})
+			currentSection = &sections[len(sections)-1]
+			currentTable = nil
+			currentH4 = nil
+			currentH5 = nil
+		case 3:
+			if currentSection == nil {
+				p.diags = append(p.diags, orphanHeading(p.line(h), "###", "##"))
+				continue
+			}
+			part1, part2 := splitH3Title(title)
+			currentSection.Tables = append(currentSection.Tables, Table{H3Part1: part1, H3Part2: part2})
+			currentTable = &currentSection.Tables[len(currentSection.Tables)-1]
+			currentH4 = nil
+			currentH5 = nil
+		case 4:
+			if currentTable == nil {
+				p.diags = append(p.diags, orphanHeading(p.line(h), "####", "###"))
+				continue
+			}
+			currentTable.H4Blocks = append(currentTable.H4Blocks, H4Block{Title: title})
+			currentH4 = &currentTable.H4Blocks[len(currentTable.H4Blocks)-1]
+			currentH5 = nil
+		case 5:
+			if currentH4 == nil {
+				p.diags = append(p.diags, orphanHeading(p.line(h), "#####", "####"))
+				continue
+			}
+			line := p.line(h)
+			if _, ok := parseHours(title); !ok && checkHours && !isMergeMarker(title) {
+				p.diags = append(p.diags, cli.Warningf(line, 1, "class-hours-invalid", "无法识别课时 %q，应写作 0.5H、2学时或 45分钟，不计入合计", title))
+			}
+			currentH4.H5Blocks = append(currentH4.H5Blocks, H5Block{Title: title, Line: line})
+			currentH5 = &currentH4.H5Blocks[len(currentH4.H5Blocks)-1]
+		}
+	}
+	for i := range sections {
+		for j := range sections[i].Tables {
+			for k := range sections[i].Tables[j].H4Blocks {
+				h4 := &sections[i].Tables[j].H4Blocks[k]
+				for l := range h4.H5Blocks {
+					p.diags = append(p.diags, resolveFields(&h4.H5Blocks[l], fields)...)
+				}
+			}
+		}
+	}
+	return sections, p.diags
+}
+
+// paragraphs 将五级标题下的一个块级元素转为内容块，并识别内容标签：
+// 段落开头的 "学习内容：" 或定义列表的术语
+func (p *mdParser) paragraphs(n ast.Node) []Paragraph {
+	if dl, ok := n.(*east.DefinitionList); ok {
+		var paras []Paragraph
+		label := ""
+		for child := dl.FirstChild(); child != nil; child = child.NextSibling() {
+			if child.Kind() == east.KindDefinitionTerm {
+				term := p.blockText(child)
+				if label = fieldName(term, p.fields); label == "" {
+					p.diags = append(p.diags, cli.Warningf(p.line(child), 1, "content-unknown-label", "%q 不是内容标签，应为 %s 之一", term, strings.Join(p.fields, "、")))
+				}
+				continue
+			}
+			if label == "" {
+				continue
+			}
+			paras = append(paras, Paragraph{Label: label, Text: p.blockText(child), Line: p.line(child)})
+		}
+		return paras
+	}
+
+	text := p.blockText(n)
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	label, text := splitFieldLabel(text, p.fields)
+	return []Paragraph{{Label: label, Text: strings.TrimSpace(text), Line: p.line(n)}}
+}
+
+// blockText 返回块级元素的 Markdown 原文，去掉标题标记、代码围栏和缩进；
+// 列表的每一项、引用中的每个段落各占一行，行内标记留给 renderCell 处理
+func (p *mdParser) blockText(n ast.Node) string {
+	if n.Type() != ast.TypeBlock {
+		return ""
+	}
+	if n.FirstChild() == nil || n.FirstChild().Type() != ast.TypeBlock {
+		lines := n.Lines()
+		var parts []string
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			parts = append(parts, strings.TrimRight(string(seg.Value(p.source)), "\n"))
+		}
+		return strings.TrimSpace(strings.Join(parts, "\n"))
+	}
+	var parts []string
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if t := p.blockText(child); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// line 返回块级元素第一行在输入中的行号；没有文字的元素（如空标题）返回 0
+func (p *mdParser) line(n ast.Node) int {
+	for ; n != nil && n.Type() == ast.TypeBlock; n = n.FirstChild() {
+		if lines := n.Lines(); lines.Len() > 0 {
+			return p.firstLine + bytes.Count(p.source[:lines.At(0).Start], []byte("\n"))
+		}
+	}
+	return 0
+}
+
+// lastLine 返回块级元素最后一行在输入中的行号；没有文字的元素返回 0
+func (p *mdParser) lastLine(n ast.Node) int {
+	for ; n != nil && n.Type() == ast.TypeBlock; n = n.LastChild() {
+		if lines := n.Lines(); lines.Len() > 0 {
+			return p.firstLine + bytes.Count(p.source[:lines.At(lines.Len()-1).Start], []byte("\n"))
+		}
+	}
+	return 0
+}
+
+// blankBetween 判断行号 from 与 to 之间（不含两端）是否有空行
+func (p *mdParser) blankBetween(from, to int) bool {
+	if from <= 0 || to <= 0 {
+		return true
+	}
+	lines := bytes.Split(p.source, []byte("\n"))
+	for l := from + 1; l < to; l++ {
+		if i := l - p.firstLine; i >= 0 && i < len(lines) && len(bytes.TrimSpace(lines[i])) == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package jiaoan
+
+import "testing"
+
+// testLayout 是测试用的表格布局：活动列、四个内容列和课时列
+var testLayout = Layout{
+	Columns: []Column{
+		{Name: "教学活动", Source: SourceActivity},
+		{Name: "学习内容", Source: "学习内容"},
+		{Name: "学生活动", Source: "学生活动"},
+		{Name: "教师活动", Source: "教师活动"},
+		{Name: "教学方法与手段", Source: "教学方法与手段"},
+		{Name: "课时分配", Source: SourceHours},
+	},
+}
+
+func TestParseMarkdownPositionalFields(t *testing.T) {
+	fields := testLayout.fields()
+	tests := []struct {
+		name, src string
+		want      map[string]string
+	}{
+		{
+			name: "blank-line separated",
+			src:  "介绍内容\n\n学生做事\n\n老师做事\n\n讲授法\n",
+			want: map[string]string{"学习内容": "介绍内容", "学生活动": "学生做事", "教师活动": "老师做事", "教学方法与手段": "讲授法"},
+		},
+		{
+			name: "list directly after paragraph",
+			src:  "介绍内容\n1. 第一步\n2. 第二步\n\n学生做事\n\n老师做事\n\n讲授法\n",
+			want: map[string]string{"学习内容": "介绍内容\n第一步\n第二步", "学生活动": "学生做事", "教师活动": "老师做事", "教学方法与手段": "讲授法"},
+		},
+		{
+			name: "paragraph directly after code block",
+			src:  "```\ncode\n```\n说明\n\n学生做事\n\n老师做事\n\n讲授法\n",
+			want: map[string]string{"学习内容": "code\n说明", "学生活动": "学生做事", "教师活动": "老师做事", "教学方法与手段": "讲授法"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "## 教学过程\n\n### 导入——任务\n\n#### 活动\n\n##### 1H\n\n" + tt.src
+			sections, diags := parseMarkdown(src, 1, testLayout)
+			for _, d := range diags {
+				t.Errorf("unexpected diagnostic: %s", d.Message)
+			}
+			got := sections[0].Tables[0].H4Blocks[0].H5Blocks[0].Fields
+			for _, f := range fields {
+				if got[f] != tt.want[f] {
+					t.Errorf("%s = %q, want %q", f, got[f], tt.want[f])
+				}
+			}
+		})
+	}
+}
+
+func TestParseMarkdownHoursTitles(t *testing.T) {
+	noHours := Layout{Columns: testLayout.Columns[:5]}
+	tests := []struct {
+		name   string
+		title  string
+		layout Layout
+		warn   bool
+	}{
+		{"valid hours", "0.5H", testLayout, false},
+		{"invalid hours", "半小时", testLayout, true},
+		{"merge marker", "同上", testLayout, false},
+		{"no hours column", "半小时", noHours, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "## 教学过程\n\n### 导入——任务\n\n#### 活动\n\n##### " + tt.title + "\n\n讲解\n"
+			_, diags := parseMarkdown(src, 1, tt.layout)
+			warned := false
+			for _, d := range diags {
+				if d.Code == "class-hours-invalid" {
+					warned = true
+					if d.Line != 7 {
+						t.Errorf("class-hours-invalid on line %d, want 7", d.Line)
+					}
+				}
+			}
+			if warned != tt.warn {
+				t.Errorf("class-hours-invalid reported = %v, want %v", warned, tt.warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package jiaoan
+
+// preambleHead 是各教案模板共用的包导入与字体定义，写在页面设置之前
+const preambleHead = `// 中文字号转换函数
+#import "@preview/pointless-size:0.1.2": zh
+#import "@preview/cuti:0.2.1": show-cn-fakebold
+#show: show-cn-fakebold
+
+// 定义常用字体名称
+#let FONT_XBS = ("FZXiaoBiaoSong-B05") // 方正小标宋
+#let FONT_HEI = ("STHeiti") // 黑体
+#let FONT_FS = ("STFangsong") // 仿宋
+#let FONT_KAI = ("STKaiti") // 楷体
+#let FONT_SONG = ("STSong") // 宋体
+
+`
+
+// preambleText 是各教案模板共用的正文与标题样式，写在页面设置之后
+const preambleText = `#set text(
+  lang: "zh",
+  font: FONT_SONG,
+  size: zh(5),
+  hyphenate: false,
+  tracking: -0.3pt,
+  cjk-latin-spacing: auto
+)
+
+#show heading.where(level: 2): it => {
+  align(center, par(leading: 40pt, text(font: FONT_SONG, size: zh(4), it.body)))
+}
+`
+
+// preamble 以模板的页面设置 pageSetup（#set page(...)）拼出完整的文档开头
+func preamble(pageSetup string) string {
+	return preambleHead + pageSetup + "\n" + preambleText
+}
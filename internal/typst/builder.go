@@ -0,0 +1,308 @@
+package typst
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineWidth is the width up to which a call or collection is kept on one line.
+const maxLineWidth = 80
+
+// Expr is a Typst code expression. Values built with this package serialise
+// themselves into correctly escaped source, so templates never splice user
+// text into code by hand.
+type Expr interface {
+	// code returns the source of the expression, with continuation lines
+	// indented for the given nesting depth.
+	code(depth int) string
+}
+
+// Code returns the source of e as it would appear at the top level.
+func Code(e Expr) string {
+	return e.code(0)
+}
+
+// source is an expression whose Typst source is already known to be valid.
+type source string
+
+func (s source) code(int) string { return string(s) }
+
+// Predefined literals.
+var (
+	None Expr = source("none")
+	Auto Expr = source("auto")
+)
+
+// Str returns a string literal.
+func Str(s string) Expr { return source(String(s)) }
+
+// StrOrNone returns a string literal, or none when s is empty.
+func StrOrNone(s string) Expr {
+	if s == "" {
+		return None
+	}
+	return Str(s)
+}
+
+// Int returns an integer literal.
+func Int(n int) Expr { return source(strconv.Itoa(n)) }
+
+// Bool returns a boolean literal.
+func Bool(b bool) Expr { return source(strconv.FormatBool(b)) }
+
+// lengthRe matches Typst lengths, ratios and fractions such as 13.4cm, 60%, 1fr.
+var lengthRe = regexp.MustCompile(`^-?\d+(\.\d+)?(pt|mm|cm|in|em|%|fr)$`)
+
+// ParseLength parses a length, ratio or fraction written by a user ("60%",
+// "8cm"), reporting false if s is not one.
+func ParseLength(s string) (Expr, bool) {
+	s = strings.TrimSpace(s)
+	if !lengthRe.MatchString(s) {
+		return nil, false
+	}
+	return source(s), true
+}
+
+// Length returns a length, ratio or fraction literal. The value is written by
+// the template, so an invalid one is a programming error and panics.
+func Length(s string) Expr {
+	e, ok := ParseLength(s)
+	if !ok {
+		panic(fmt.Sprintf("typst: invalid length %q", s))
+	}
+	return e
+}
+
+// identRe matches a variable or field path such as FONT_HEI, left or table.hline.
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*(\.[A-Za-z_][A-Za-z0-9_-]*)*$`)
+
+// Ident refers to a variable, function or field path defined by the template
+// (FONT_HEI, autoDateText, table.hline). It panics on anything else, so user
+// text can never be passed through it.
+func Ident(name string) Expr {
+	if !identRe.MatchString(name) {
+		panic(fmt.Sprintf("typst: invalid identifier %q", name))
+	}
+	return source(name)
+}
+
+// Op joins operands with a binary operator, e.g. Op("+", Ident("left"), Ident("horizon")).
+func Op(op string, operands ...Expr) Expr {
+	parts := make([]string, len(operands))
+	for i, e := range operands {
+		parts[i] = e.code(0)
+	}
+	return source(strings.Join(parts, " "+op+" "))
+}
+
+// Datetime returns a datetime(year:, month:, day:) value for the date of t.
+func Datetime(t time.Time) Expr {
+	return Call("datetime",
+		Named("year", Int(t.Year())),
+		Named("month", Int(int(t.Month()))),
+		Named("day", Int(t.Day())),
+	)
+}
+
+// LabelValue returns a label usable as a value in code, e.g. as link target.
+// Names that label syntax cannot express are written as label("...").
+func LabelValue(name string) Expr {
+	if IsLabelName(name) {
+		return source("<" + name + ">")
+	}
+	return Call("label", Pos(Str(name)))
+}
+
+// ---------- Arguments and calls ----------
+
+// Arg is a positional or named argument of a call, or an entry of a dictionary.
+type Arg struct {
+	name  string
+	value Expr
+	group []Arg
+}
+
+// Pos returns a positional argument.
+func Pos(v Expr) Arg { return Arg{value: v} }
+
+// Named returns a named argument or dictionary entry.
+func Named(name string, v Expr) Arg { return Arg{name: name, value: v} }
+
+// Group keeps several arguments together on one line of a multi-line call,
+// such as the cells of one table row.
+func Group(args ...Arg) Arg { return Arg{group: args} }
+
+func (a Arg) code(depth int) string {
+	if a.group != nil {
+		parts := make([]string, len(a.group))
+		for i, g := range a.group {
+			parts[i] = g.code(depth)
+		}
+		return strings.Join(parts, ", ")
+	}
+	if a.name == "" {
+		return a.value.code(depth)
+	}
+	return a.name + ": " + a.value.code(depth)
+}
+
+// CallExpr is a function call with optional trailing content block.
+type CallExpr struct {
+	fn   string
+	args []Arg
+	body *Markup
+}
+
+// Call returns a call of fn, which must be a template-defined function or
+// method path (figure, table.cell, figure-align.update).
+func Call(fn string, args ...Arg) *CallExpr {
+	Ident(fn) // validate
+	return &CallExpr{fn: fn, args: args}
+}
+
+// Arg appends arguments to the call.
+func (c *CallExpr) Arg(args ...Arg) *CallExpr {
+	c.args = append(c.args, args...)
+	return c
+}
+
+// Body sets the trailing content block: fn(args)[body].
+func (c *CallExpr) Body(body Markup) *CallExpr {
+	c.body = &body
+	return c
+}
+
+func (c *CallExpr) code(depth int) string {
+	s := c.fn
+	if len(c.args) > 0 || c.body == nil {
+		s += list(c.args, depth, utf8.RuneCountInString(c.fn), "", "")
+	}
+	if c.body != nil {
+		s += "[" + string(*c.body) + "]"
+	}
+	return s
+}
+
+// list formats items as "(a, b)" on one line when it fits after lead columns of
+// preceding text, otherwise one item per line with a trailing comma. single and
+// empty override the one-item and zero-item forms, which differ for arrays and
+// dictionaries.
+func list(items []Arg, depth, lead int, single, empty string) string {
+	if len(items) == 0 {
+		if empty != "" {
+			return empty
+		}
+		return "()"
+	}
+	parts := make([]string, len(items))
+	multiline := false
+	width := lead + 2*depth
+	for i, item := range items {
+		parts[i] = item.code(depth + 1)
+		width += utf8.RuneCountInString(parts[i]) + 2
+		if strings.Contains(parts[i], "\n") {
+			multiline = true
+		}
+	}
+	if !multiline && width <= maxLineWidth {
+		if len(parts) == 1 && single != "" {
+			return "(" + parts[0] + single + ")"
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	}
+	indent := strings.Repeat("  ", depth+1)
+	var b strings.Builder
+	b.WriteString("(\n")
+	for _, p := range parts {
+		b.WriteString(indent + p + ",\n")
+	}
+	b.WriteString(strings.Repeat("  ", depth) + ")")
+	return b.String()
+}
+
+// arrayExpr is an array literal.
+type arrayExpr []Expr
+
+// Array returns an array literal; a single item keeps its trailing comma.
+func Array(items ...Expr) Expr { return arrayExpr(items) }
+
+// StrArray returns an array of string literals.
+func StrArray(items []string) Expr {
+	exprs := make([]Expr, len(items))
+	for i, s := range items {
+		exprs[i] = Str(s)
+	}
+	return arrayExpr(exprs)
+}
+
+func (a arrayExpr) code(depth int) string {
+	args := make([]Arg, len(a))
+	for i, e := range a {
+		args[i] = Pos(e)
+	}
+	return list(args, depth, 0, ",", "()")
+}
+
+// dictExpr is a dictionary literal.
+type dictExpr []Arg
+
+// Dict returns a dictionary literal built from Named entries.
+func Dict(entries ...Arg) Expr { return dictExpr(entries) }
+
+func (d dictExpr) code(depth int) string {
+	return list(d, depth, 0, "", "(:)")
+}
+
+// Content returns a content block [markup] usable as a value.
+func Content(m Markup) Expr { return source("[" + string(m) + "]") }
+
+// TableCell returns table.cell(args)[body].
+func TableCell(body Markup, args ...Arg) Expr {
+	return Call("table.cell", args...).Body(body)
+}
+
+// ---------- Markup ----------
+
+// Markup is Typst markup that is safe to embed: escaped text or markup built
+// with this package. Concatenate pieces with +.
+type Markup string
+
+// Text returns s escaped as markup.
+func Text(s string) Markup { return Markup(EscapeContent(s)) }
+
+// Embed embeds a code expression in markup: #expr. Use it where the expression
+// ends a line or is followed by markup that cannot continue it.
+func Embed(e Expr) Markup { return Markup("#" + e.code(0)) }
+
+// Inline embeds a code expression in running text, terminated with ";" so that
+// following text such as "(" or ".x" cannot continue the expression.
+func Inline(e Expr) Markup { return Embed(e) + ";" }
+
+// Let returns a #let binding of name to v.
+func Let(name string, v Expr) Markup {
+	Ident(name) // validate
+	return Markup("#let " + name + " = " + v.code(0))
+}
+
+// Set returns a #set rule for the element fn.
+func Set(fn string, args ...Arg) Markup {
+	return Markup("#set " + Call(fn, args...).code(0))
+}
+
+// Heading returns a heading of the given level: "== body".
+func Heading(level int, body Markup) Markup {
+	return Markup(strings.Repeat("=", level)+" ") + body
+}
+
+// WithLabel attaches a label to the element that m ends with: "m <name>".
+// An empty name leaves m unchanged.
+func (m Markup) WithLabel(name string) Markup {
+	if name == "" {
+		return m
+	}
+	return m + " " + Markup(Label(name))
+}
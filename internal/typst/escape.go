@@ -1,21 +1,176 @@
 package typst
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 // EscapeString escapes s for safe embedding inside a Typst string literal ("...").
-// Neutralizes \, ", and # to prevent code injection.
+// Neutralizes \ and " and writes control characters as escape sequences, so the
+// literal can neither be terminated early nor span lines.
 func EscapeString(s string) string {
-	s = strings.ReplaceAll(s, `\`, `\\`)
-	s = strings.ReplaceAll(s, `"`, `\"`)
-	s = strings.ReplaceAll(s, `#`, `\#`)
-	return s
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
 }
 
-// EscapeContent escapes s for safe embedding inside a Typst content block ([...]).
-// Neutralizes \, ], and # to prevent code injection.
+// String returns s as a complete Typst string literal, quotes included.
+func String(s string) string {
+	return `"` + EscapeString(s) + `"`
+}
+
+// EscapeContent escapes s for safe embedding in Typst markup, either at the top
+// level or inside a content block ([...]). Every character that can start markup
+// syntax is backslash-escaped:
+//   - code, content and labels: # [ ] < @
+//   - emphasis, raw and math: * _ ` $
+//   - comments and shorthands: // /* -- -? ... ~
+//   - at the start of a line: headings (=), lists (- +), terms (/) and
+//     numbered lists ("1. ")
+//
+// Bare http:// and https:// URLs are written as they are, so Typst still links
+// them automatically; a backslash would end the link. Line breaks are kept, so
+// "\n\n" still separates paragraphs. The start of s is treated as the start of a
+// line, since the caller may place it there.
 func EscapeContent(s string) string {
-	s = strings.ReplaceAll(s, `\`, `\\`)
-	s = strings.ReplaceAll(s, `]`, `\]`)
-	s = strings.ReplaceAll(s, `#`, `\#`)
-	return s
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/8)
+	runes := []rune(s)
+	lineStart := true // only whitespace since the last newline
+	enumDigits := false
+	at := 0 // byte offset of runes[i] in s
+	for i := 0; i < len(runes); at, i = at+utf8.RuneLen(runes[i]), i+1 {
+		r := runes[i]
+		if n := urlLen(s[at:]); n > 0 {
+			// URLs are ASCII, so n bytes are n runes.
+			b.WriteString(s[at : at+n])
+			at, i = at+n-1, i+n-1
+			lineStart, enumDigits = false, false
+			continue
+		}
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		prev := rune(0)
+		if i > 0 {
+			prev = runes[i-1]
+		}
+
+		escape := false
+		switch r {
+		case '\\', '#', '[', ']', '<', '@', '*', '_', '`', '$', '~':
+			escape = true
+		case '=', '+':
+			escape = lineStart
+		case '-':
+			escape = lineStart || next == '-' || next == '?' || prev == '-'
+		case '/':
+			escape = lineStart || next == '/' || next == '*'
+		case '.':
+			escape = next == '.' || prev == '.' ||
+				enumDigits && (next == 0 || next == ' ' || next == '\t' || next == '\n')
+		}
+		if escape {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+
+		switch {
+		case r == '\n':
+			lineStart, enumDigits = true, false
+		case r == ' ' || r == '\t':
+			enumDigits = false
+		case r >= '0' && r <= '9' && (lineStart || enumDigits):
+			lineStart, enumDigits = false, true
+		default:
+			lineStart, enumDigits = false, false
+		}
+	}
+	return b.String()
+}
+
+// urlLen returns the length in bytes of the bare URL at the start of s, or 0
+// if there is none. Like Typst's own link syntax, the URL runs over the
+// characters a URL may contain; it stops before brackets, which must be escaped,
+// and drops trailing punctuation, which ends a sentence rather than the URL.
+func urlLen(s string) int {
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return 0
+	}
+	n := 0
+	for n < len(s) && isURLByte(s[n]) {
+		n++
+	}
+	for n > 0 && strings.IndexByte(".,;:?!'", s[n-1]) >= 0 {
+		n--
+	}
+	if strings.HasSuffix(s[:n], "//") {
+		return 0 // a scheme without a host
+	}
+	return n
+}
+
+func isURLByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("!#$%&*+,-./:;=?@_~'", c) >= 0
+}
+
+// Raw returns a raw element call (without the leading #) that displays text
+// verbatim. Passing the text as a string literal, rather than between backtick
+// fences, means no content can terminate the raw block early.
+func Raw(text, lang string, block bool) Expr {
+	call := Call("raw", Pos(Str(text)))
+	if block {
+		call.Arg(Named("block", Bool(true)))
+	}
+	if lang != "" {
+		call.Arg(Named("lang", Str(lang)))
+	}
+	return call
+}
+
+// labelRe matches the names Typst accepts in <label> syntax.
+var labelRe = regexp.MustCompile(`^[\p{L}\p{N}\p{M}_:.-]+$`)
+
+// IsLabelName reports whether name can be written as a Typst label <name>.
+func IsLabelName(name string) bool {
+	return labelRe.MatchString(name)
+}
+
+// Label returns name as a Typst label <name>, replacing every character that
+// label syntax does not allow with "-".
+func Label(name string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || strings.ContainsRune("_:.-", r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	if name == "" {
+		b.WriteByte('-')
+	}
+	b.WriteByte('>')
+	return b.String()
 }
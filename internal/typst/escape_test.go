@@ -0,0 +1,113 @@
+package typst
+
+import "testing"
+
+func TestEscapeContent(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// Always escaped.
+		{`a\b`, `a\\b`},
+		{"#x", `\#x`},
+		{"[a]", `\[a\]`},
+		{"<a>", `\<a>`},
+		{"@a", `\@a`},
+		{"*a*", `\*a\*`},
+		{"_a_", `\_a\_`},
+		{"`a`", "\\`a\\`"},
+		{"$a$", `\$a\$`},
+		{"a~b", `a\~b`},
+		// Syntax only at the start of a line.
+		{"= h", `\= h`},
+		{"a = b", "a = b"},
+		{"+ a", `\+ a`},
+		{"a + b", "a + b"},
+		{"- a", `\- a`},
+		{"a - b", "a - b"},
+		{"/ t: d", `\/ t: d`},
+		{"a/b", "a/b"},
+		{"1. a", `1\. a`},
+		{"2025.3.15", "2025.3.15"},
+		{"a. b", "a. b"},
+		{"x\n  - a", "x\n  \\- a"},
+		{"x\n= h", "x\n\\= h"},
+		// Shorthands and comments.
+		{"a--b", `a\-\-b`},
+		{"a-?b", `a\-?b`},
+		{"a...b", `a\.\.\.b`},
+		{"a//b", `a\//b`},
+		{"a/*b*/", `a\/\*b\*/`},
+		// Bare URLs keep Typst's automatic linking.
+		{"见 http://x.com/a_b#c。", "见 http://x.com/a_b#c。"},
+		{"https://x.com/a*b.", "https://x.com/a*b."},
+		{"ftp://x.com", `ftp:\//x.com`},
+		{"http://", `http:\//`},
+		{"[http://x.com]", `\[http://x.com\]`},
+		// Paragraphs are kept.
+		{"a\n\nb", "a\n\nb"},
+	}
+	for _, tt := range tests {
+		got := EscapeContent(tt.in)
+		if got != tt.want {
+			t.Errorf("EscapeContent(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if errs := Validate("#[" + got + "]"); len(errs) > 0 {
+			t.Errorf("EscapeContent(%q) = %q, which does not validate: %v", tt.in, got, errs)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a", `"a"`},
+		{`a\b`, `"a\\b"`},
+		{`"a"`, `"\"a\""`},
+		{"a\nb", `"a\nb"`},
+		{"a\rb\tc", `"a\rb\tc"`},
+		{"#[a]$*_`@<", "\"#[a]$*_`@<\""},
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRaw(t *testing.T) {
+	tests := []struct {
+		text, lang string
+		block      bool
+		want       string
+	}{
+		{"a`b", "", false, "raw(\"a`b\")"},
+		{"```\n\"x\"", "go", true, "raw(\"```\\n\\\"x\\\"\", block: true, lang: \"go\")"},
+	}
+	for _, tt := range tests {
+		if got := Code(Raw(tt.text, tt.lang, tt.block)); got != tt.want {
+			t.Errorf("Raw(%q, %q, %v) = %s, want %s", tt.text, tt.lang, tt.block, got, tt.want)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		in, want string
+		valid    bool
+	}{
+		{"fig:a-1", "<fig:a-1>", true},
+		{"表1", "<表1>", true},
+		{"a b", "<a-b>", false},
+		{"a>#b", "<a--b>", false},
+		{"", "<->", false},
+	}
+	for _, tt := range tests {
+		if got := Label(tt.in); got != tt.want {
+			t.Errorf("Label(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := IsLabelName(tt.in); got != tt.valid {
+			t.Errorf("IsLabelName(%q) = %v, want %v", tt.in, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,512 @@
+package typst
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError is a problem found by Validate, located by 1-based line and column.
+type SyntaxError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// builtinNames are the Typst functions and values that template output may
+// use in code. Functions that read files or evaluate code (read,
+// eval, plugin, json, ...) are deliberately absent: in generated output they
+// can only come from unescaped user text.
+var builtinNames = map[string]bool{
+	"align": true, "block": true, "box": true, "calc": true, "counter": true,
+	"datetime": true, "document": true, "emph": true, "enum": true, "figure": true,
+	"footnote": true, "grid": true, "h": true, "heading": true, "here": true,
+	"image": true, "label": true, "line": true, "linebreak": true, "link": true,
+	"list": true, "luma": true, "measure": true, "metadata": true, "numbering": true,
+	"pad": true, "page": true, "pagebreak": true, "par": true, "place": true,
+	"query": true, "quote": true, "range": true, "raw": true, "ref": true, "rgb": true,
+	"scale": true, "stack": true, "state": true, "str": true, "strong": true,
+	"table": true, "text": true, "underline": true, "v": true,
+	// Alignments.
+	"left": true, "center": true, "right": true, "start": true, "end": true,
+	"top": true, "horizon": true, "bottom": true,
+}
+
+// Keywords that may follow # in markup.
+var (
+	statementKeywords = map[string]bool{"let": true, "set": true, "show": true, "if": true, "for": true, "while": true, "context": true}
+	literalKeywords   = map[string]bool{"none": true, "auto": true, "true": true, "false": true}
+)
+
+// codeKeywords are the keywords and literals of code mode, which are never
+// looked up as names.
+var codeKeywords = map[string]bool{
+	"let": true, "set": true, "show": true, "if": true, "else": true, "for": true,
+	"in": true, "while": true, "context": true, "return": true, "break": true,
+	"continue": true, "import": true, "include": true, "not": true, "and": true,
+	"or": true, "as": true, "none": true, "auto": true, "true": true, "false": true,
+}
+
+// Validate tokenises src as a Typst document (markup, code, strings, content
+// blocks, raw text, math and comments) and reports unbalanced brackets,
+// unterminated strings, raw text and comments, and names used in code that the
+// document neither binds itself nor takes from the builtins templates use.
+// Such names are what unescaped user text looks like, so Validate catches
+// injection regressions without the Typst compiler. Errors are in source order.
+//
+// The scanner makes two passes. The first only collects the names that let
+// bindings, imports, parameters and loop variables bind in code, so escaped
+// user text that merely reads "let read = 1" binds nothing; the second
+// reports errors.
+func Validate(src string) []SyntaxError {
+	collect := &validator{src: src, known: map[string]bool{}, collecting: true}
+	collect.markup(-1)
+	v := &validator{src: src, known: collect.known}
+	v.markup(-1)
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		a, b := v.errs[i], v.errs[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+	return v.errs
+}
+
+// validator is the state of one Validate run.
+type validator struct {
+	src        string
+	pos        int
+	known      map[string]bool // names the document binds in code
+	collecting bool            // binding names only, errors are dropped
+	errs       []SyntaxError
+}
+
+// errorf records an error at byte offset at.
+func (v *validator) errorf(at int, format string, args ...interface{}) {
+	if v.collecting {
+		return
+	}
+	line := 1 + strings.Count(v.src[:at], "\n")
+	col := 1 + utf8.RuneCountInString(v.src[strings.LastIndexByte(v.src[:at], '\n')+1:at])
+	v.errs = append(v.errs, SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) hasPrefix(s string) bool {
+	return strings.HasPrefix(v.src[v.pos:], s)
+}
+
+// markup scans markup up to the "]" closing the content block opened at open,
+// or to the end of input when open is -1.
+func (v *validator) markup(open int) {
+	for v.pos < len(v.src) {
+		switch c := v.src[v.pos]; {
+		case c == '\\':
+			v.pos++
+			if v.pos < len(v.src) {
+				_, size := utf8.DecodeRuneInString(v.src[v.pos:])
+				v.pos += size
+			}
+		case c == 'h' && urlLen(v.src[v.pos:]) > 0:
+			v.pos += urlLen(v.src[v.pos:]) // a link, in which // and # are not syntax
+		case c == '/' && v.hasPrefix("//"):
+			v.lineComment()
+		case c == '/' && v.hasPrefix("/*"):
+			v.blockComment()
+		case c == '`':
+			v.raw()
+		case c == '$':
+			v.math()
+		case c == '[':
+			start := v.pos
+			v.pos++
+			v.markup(start)
+		case c == ']':
+			if open >= 0 {
+				v.pos++
+				return
+			}
+			v.errorf(v.pos, "unbalanced ]")
+			v.pos++
+		case c == '#':
+			v.embedded()
+		default:
+			v.pos++
+		}
+	}
+	if open >= 0 {
+		v.errorf(open, "unclosed [")
+	}
+}
+
+// embedded scans a code expression embedded in markup with #.
+func (v *validator) embedded() {
+	start := v.pos
+	v.pos++
+	if v.pos >= len(v.src) {
+		v.errorf(start, "expected expression after #")
+		return
+	}
+	switch c := v.src[v.pos]; {
+	case isIdentStart(c):
+		name := v.ident()
+		switch {
+		case statementKeywords[name]:
+			v.statement()
+		case literalKeywords[name]:
+		case name == "import" || name == "include":
+			// Only published packages may be imported; a file path could expose
+			// local files.
+			if !strings.HasPrefix(strings.TrimLeft(v.src[v.pos:], " "), `"@preview/`) {
+				v.errorf(start, "unexpected #%s", name)
+			}
+			v.importItems()
+		default:
+			if !v.known[name] && !builtinNames[name] {
+				v.errorf(start, "unexpected code expression #%s", name)
+			}
+			v.postfix()
+		}
+	case c == '(':
+		v.pos++
+		v.code(start+1, ')')
+		v.postfix()
+	case c == '{':
+		v.pos++
+		v.code(start+1, '}')
+	case c == '[':
+		v.pos++
+		v.markup(start + 1)
+	default:
+		v.errorf(start, `unexpected # (write \# for a literal hash)`)
+	}
+}
+
+// importItems scans the rest of an import statement and binds the names it
+// imports, as in #import "@preview/cuti:0.2.1": show-cn-fakebold.
+func (v *validator) importItems() {
+	for v.pos < len(v.src) && v.src[v.pos] == ' ' {
+		v.pos++
+	}
+	if v.pos < len(v.src) && v.src[v.pos] == '"' {
+		v.str()
+	}
+	if !v.hasPrefix(":") {
+		return
+	}
+	v.pos++
+	end := v.pos
+	for end < len(v.src) && v.src[end] != '\n' && v.src[end] != ';' {
+		end++
+	}
+	for _, item := range strings.Split(v.src[v.pos:end], ",") {
+		if _, alias, ok := strings.Cut(item, " as "); ok {
+			item = alias
+		}
+		if name := strings.TrimSpace(item); name != "" {
+			v.known[name] = true
+		}
+	}
+	v.pos = end
+}
+
+// postfix scans field accesses, argument lists and trailing content blocks
+// that continue an embedded expression: .field, (args), [body].
+func (v *validator) postfix() {
+	for v.pos < len(v.src) {
+		switch v.src[v.pos] {
+		case '.':
+			if v.pos+1 >= len(v.src) || !isIdentStart(v.src[v.pos+1]) {
+				return
+			}
+			v.pos++
+			v.ident()
+		case '(':
+			open := v.pos
+			v.pos++
+			v.code(open, ')')
+		case '[':
+			open := v.pos
+			v.pos++
+			v.markup(open)
+		default:
+			return
+		}
+	}
+}
+
+// statement scans a #let, #set, #show or control-flow statement up to the end
+// of its line, a ";" or the "]" of the enclosing content block.
+func (v *validator) statement() {
+	for v.pos < len(v.src) {
+		if c := v.src[v.pos]; c == '\n' || c == ';' || c == ']' {
+			return
+		}
+		v.codeToken()
+	}
+}
+
+// code scans code up to the closer of the bracket opened at open.
+func (v *validator) code(open int, closer byte) {
+	for v.pos < len(v.src) {
+		switch c := v.src[v.pos]; c {
+		case closer:
+			v.pos++
+			return
+		case ')', '}', ']':
+			v.errorf(v.pos, "unbalanced %c (expected %c)", c, closer)
+			v.pos++
+		default:
+			v.codeToken()
+		}
+	}
+	v.errorf(open, "unclosed %c", v.src[open])
+}
+
+// codeToken scans one token of code, including any bracketed group it opens.
+func (v *validator) codeToken() {
+	switch c := v.src[v.pos]; {
+	case isIdentStart(c):
+		start := v.pos
+		v.name(start, v.ident())
+	case isDigit(c):
+		v.number()
+	case c == '<' && labelAt(v.src[v.pos:]) > 0:
+		v.pos += labelAt(v.src[v.pos:])
+	case c == '(':
+		open := v.pos
+		v.pos++
+		v.code(open, ')')
+		v.bindParams(open)
+	case c == '{':
+		open := v.pos
+		v.pos++
+		v.code(open, '}')
+	case c == '[':
+		open := v.pos
+		v.pos++
+		v.markup(open)
+	case c == ')' || c == '}' || c == ']':
+		v.errorf(v.pos, "unbalanced %c", c)
+		v.pos++
+	case c == '"':
+		v.str()
+	case c == '/' && v.hasPrefix("//"):
+		v.lineComment()
+	case c == '/' && v.hasPrefix("/*"):
+		v.blockComment()
+	case c == '`':
+		v.raw()
+	case c == '$':
+		v.math()
+	default:
+		v.pos++
+	}
+}
+
+// name checks the identifier name read in code at start, or binds it where
+// the code declares it. Fields and methods after "." and argument or
+// dictionary keys before ":" are not looked up.
+func (v *validator) name(start int, name string) {
+	before := strings.TrimRight(v.src[:start], " \t")
+	after := strings.TrimLeft(v.src[v.pos:], " \t")
+	switch {
+	case codeKeywords[name]:
+	case strings.HasSuffix(before, ".") && len(before) == start:
+	case endsWithWord(before, "let"), strings.HasPrefix(after, "=>"),
+		endsWithWord(before, "for") && strings.HasPrefix(after, "in"):
+		v.known[name] = true
+	case strings.HasPrefix(after, ":"):
+	case !v.known[name] && !builtinNames[name]:
+		v.errorf(start, "unexpected name %s in code", name)
+	}
+}
+
+// bindParams binds the names declared by the parenthesized group opened at
+// open, which the scanner has just closed, when it is a parameter list or a
+// destructuring pattern: let f(a, b: 1) = ..., (a, b) => ..., let (a, b) = ...
+// or for (k, v) in ....
+func (v *validator) bindParams(open int) {
+	before := strings.TrimRight(v.src[:open], " \t")
+	fn := strings.TrimRight(before, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-")
+	if !strings.HasPrefix(strings.TrimLeft(v.src[v.pos:], " \t"), "=>") &&
+		!endsWithWord(before, "let") && !endsWithWord(before, "for") &&
+		!(fn != before && endsWithWord(strings.TrimRight(fn, " \t"), "let")) {
+		return
+	}
+	depth, item := 0, open+1
+	for i := open + 1; i < v.pos-1; i++ {
+		switch v.src[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				v.bindParam(v.src[item:i])
+				item = i + 1
+			}
+		}
+	}
+	v.bindParam(v.src[item : v.pos-1])
+}
+
+// bindParam binds the name of one parameter: a, b: default or ..rest.
+func (v *validator) bindParam(param string) {
+	param = strings.TrimPrefix(strings.TrimSpace(param), "..")
+	end := 0
+	for end < len(param) && (isIdentStart(param[end]) || end > 0 && (isDigit(param[end]) || param[end] == '-')) {
+		end++
+	}
+	if end > 0 {
+		v.known[param[:end]] = true
+	}
+}
+
+// number scans a numeric literal with an optional unit: 2, 0.5, 2.54cm, 50%.
+func (v *validator) number() {
+	for v.pos < len(v.src) && (isDigit(v.src[v.pos]) || v.src[v.pos] == '.' && v.pos+1 < len(v.src) && isDigit(v.src[v.pos+1])) {
+		v.pos++
+	}
+	for v.pos < len(v.src) && (v.src[v.pos] >= 'a' && v.src[v.pos] <= 'z' || v.src[v.pos] == '%') {
+		v.pos++
+	}
+}
+
+// str scans a string literal.
+func (v *validator) str() {
+	start := v.pos
+	for v.pos++; v.pos < len(v.src); v.pos++ {
+		switch v.src[v.pos] {
+		case '\\':
+			v.pos++
+		case '"':
+			v.pos++
+			return
+		}
+	}
+	v.errorf(start, "unterminated string")
+}
+
+// raw scans raw text delimited by a run of backticks; two backticks are empty raw.
+func (v *validator) raw() {
+	start := v.pos
+	for v.pos < len(v.src) && v.src[v.pos] == '`' {
+		v.pos++
+	}
+	fence := v.src[start:v.pos]
+	if len(fence) == 2 {
+		return
+	}
+	end := strings.Index(v.src[v.pos:], fence)
+	if end < 0 {
+		v.errorf(start, "unterminated raw text")
+		v.pos = len(v.src)
+		return
+	}
+	v.pos += end + len(fence)
+}
+
+// math scans an equation between dollar signs.
+func (v *validator) math() {
+	start := v.pos
+	for v.pos++; v.pos < len(v.src); {
+		switch v.src[v.pos] {
+		case '\\':
+			v.pos += 2
+		case '"':
+			v.str()
+		case '#':
+			v.embedded()
+		case '$':
+			v.pos++
+			return
+		default:
+			v.pos++
+		}
+	}
+	v.errorf(start, "unterminated math")
+}
+
+// lineComment skips a // comment.
+func (v *validator) lineComment() {
+	if end := strings.IndexByte(v.src[v.pos:], '\n'); end >= 0 {
+		v.pos += end
+	} else {
+		v.pos = len(v.src)
+	}
+}
+
+// blockComment skips a /* */ comment, which may nest.
+func (v *validator) blockComment() {
+	start := v.pos
+	depth := 0
+	for v.pos < len(v.src) {
+		switch {
+		case v.hasPrefix("/*"):
+			depth++
+			v.pos += 2
+		case v.hasPrefix("*/"):
+			depth--
+			v.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			v.pos++
+		}
+	}
+	v.errorf(start, "unterminated comment")
+}
+
+// ident scans an identifier such as fit-image or FONT_HEI.
+func (v *validator) ident() string {
+	start := v.pos
+	for v.pos < len(v.src) {
+		c := v.src[v.pos]
+		if !isIdentStart(c) && !(c >= '0' && c <= '9') && c != '-' {
+			break
+		}
+		v.pos++
+	}
+	return v.src[start:v.pos]
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// endsWithWord reports whether s ends with the whole word w.
+func endsWithWord(s, w string) bool {
+	if !strings.HasSuffix(s, w) {
+		return false
+	}
+	rest := s[:len(s)-len(w)]
+	if rest == "" {
+		return true
+	}
+	c := rest[len(rest)-1]
+	return !isIdentStart(c) && !isDigit(c) && c != '-' && c != '.'
+}
+
+// labelAt returns the length of the label such as <doc-body-end> at the start
+// of s, or 0 if s does not start with one.
+func labelAt(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '>':
+			if i == 1 {
+				return 0
+			}
+			return i + 1
+		case !isIdentStart(c) && !isDigit(c) && c != '-' && c != '.' && c != ':':
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package typst
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRejectsInjection(t *testing.T) {
+	tests := []struct {
+		name, src string
+	}{
+		{"call", `#read("/etc/passwd")`},
+		{"code block", `#{read("/etc/passwd")}`},
+		{"parenthesized", `#(eval("1"))`},
+		{"string", `#"x"`},
+		{"argument", `#text(read("/etc/passwd"))`},
+		{"let value", `#let f = read`},
+		{"math", `$#read("/etc/passwd")$`},
+		{"file import", `#import "/secret.typ": x`},
+		{"file include", `#include "/secret.typ"`},
+		{"let in text", "正文 let read = 1\n\n#read(\"/etc/passwd\")"},
+		{"closure in text", "正文 read => 1\n\n#read(\"/etc/passwd\")"},
+		{"for in text", "for read in x\n\n#read(\"/etc/passwd\")"},
+		{"stray hash", `# 标题`},
+		{"unclosed bracket", `#strong[text`},
+		{"unbalanced bracket", `text]`},
+		{"unterminated string", `#text("x)`},
+		{"unterminated raw", "```go\nx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := Validate(tt.src); len(errs) == 0 {
+				t.Errorf("Validate(%q) = no errors, want an error", tt.src)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsTemplateOutput(t *testing.T) {
+	tests := []struct {
+		name, src string
+	}{
+		{"imports", `#import "@preview/pointless-size:0.1.1": zh
+#import "@preview/cuti:0.2.1": show-cn-fakebold
+#show: show-cn-fakebold
+#set text(size: zh(3))`},
+		{"let", `#let FONT_SONG = ("Times New Roman", "SimSun")
+#set text(font: FONT_SONG, size: 16pt, tracking: -0.3pt)`},
+		{"function", `#let fit-image(path, max-width: 100%, ..args) = context {
+  let size = measure(image(path))
+  let width = calc.min(size.width, max-width)
+  image(path, width: width, ..args)
+}
+#fit-image("a.png", max-width: 50%)`},
+		{"show rule", `#show heading.where(level: 2): it => block(above: 1em)[#text(weight: "bold", it.body)]`},
+		{"loop", `#for (i, name) in ("a", "b").enumerate() [#(i + 1). #name]`},
+		{"table", `#table(
+  columns: (2.3cm, auto, 1fr),
+  align: (left + horizon, center, right),
+  table.header([*环节*], [内容], [课时]),
+  table.cell(rowspan: 2)[导入], [x], [1],
+)`},
+		{"label", `#context {
+  let end = query(<doc-body-end>)
+  if end.len() > 0 { str(end.first().location().page()) }
+}`},
+		{"escaped text", `正文 let read = 1，read => 2，\#read("x")，http://example.com`},
+		{"math", `$x^2 + #h(1em) "text"$`},
+		{"comments", "// note\n/* block /* nested */ */ text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := Validate(tt.src); len(errs) > 0 {
+				msgs := make([]string, len(errs))
+				for i, err := range errs {
+					msgs[i] = err.Error()
+				}
+				t.Errorf("Validate(%q) = %s, want no errors", tt.src, strings.Join(msgs, "; "))
+			}
+		})
+	}
+}
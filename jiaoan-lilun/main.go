@@ -0,0 +1,46 @@
+package main
+
+import (
+	_ "embed"
+
+	"github.com/Presto-io/presto-official-templates/internal/cli"
+	"github.com/Presto-io/presto-official-templates/internal/frontmatter"
+	"github.com/Presto-io/presto-official-templates/internal/jiaoan"
+)
+
+//go:embed manifest.json
+var manifestJSON string
+
+//go:embed example.md
+var exampleMD string
+
+// frontMatterSchema 是内嵌 manifest 中的 frontmatterSchema，用于校验课程信息
+var frontMatterSchema = frontmatter.MustParseSchema(manifestJSON)
+
+// defaultLayout 是未在 front matter 中配置时使用的理论教案教学过程表格
+var defaultLayout = jiaoan.Layout{
+	Columns: []jiaoan.Column{
+		{Name: "教学步骤", Width: "2cm", Source: jiaoan.SourceActivity, Numbered: true},
+		{Name: "教学内容", Width: "auto", Source: "教学内容", Numbered: true},
+		{Name: "教师活动", Width: "auto", Source: "教师活动", Numbered: true},
+		{Name: "学生活动", Width: "auto", Source: "学生活动", Numbered: true},
+		{Name: "设计意图", Width: "2.4cm", Source: "设计意图"},
+		{Name: "时间", Width: "1.3cm", Source: jiaoan.SourceHours, Unit: "分钟"},
+	},
+	StageLabel:  "教学环节",
+	UnitLabel:   "教学任务",
+	TextWidthCM: 21.0 - 2.2 - 2.2, // 纵向 A4 减去 pageSetup 中的左右页边距
+}
+
+func main() {
+	cli.Run(manifestJSON, exampleMD, func(input string) (string, []cli.Diagnostic) {
+		return jiaoan.Convert(input, frontMatterSchema, pageSetup, defaultLayout)
+	})
+}
+
+// pageSetup 是纵向 A4 页面设置，字体与正文样式由 jiaoan 包统一提供
+const pageSetup = `#set page(
+  paper: "a4",
+  margin: (top: 2.54cm, bottom: 2.54cm, left: 2.2cm, right: 2.2cm)
+)
+`